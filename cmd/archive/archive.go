@@ -0,0 +1,195 @@
+package archive
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/archive"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+	"github.com/dkaslovsky/thread-safe/pkg/thread"
+)
+
+// Run executes the package's (sub)command, recording version as the version of the application that
+// saved each thread
+func Run(appName string, version string, args []string) error {
+	cmd := flag.NewFlagSet("import", flag.ExitOnError)
+	opts := &cmdOpts{version: version}
+	attachOpts(cmd, opts)
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		if errors.Is(err, errs.ErrNoArgs) {
+			cmd.Usage()
+			return nil
+		}
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	a, err := archive.Open(opts.archiveDir)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	prefix := opts.prefix
+	if prefix == "" {
+		prefix = a.Handle()
+	}
+
+	client := a.Client()
+	for _, lastTweetID := range a.ThreadEnds() {
+		// Name the thread by its last tweet, which is unique to each chain even when chains fork from the
+		// same tweet, so that only threads saved by an earlier import are skipped
+		th := thread.New(opts.path, fmt.Sprintf("%s %s", prefix, lastTweetID))
+		if th.Dir.Exists() {
+			fmt.Printf("skipping %s: already imported\n", th.Dir)
+			continue
+		}
+
+		err := th.Load(client, lastTweetID, thread.DefaultStopPolicy())
+		if err != nil {
+			return fmt.Errorf("failed to parse thread ending with tweet %s: %w", lastTweetID, err)
+		}
+
+		sErr := save(th, a, opts)
+		if sErr != nil {
+			return sErr
+		}
+		fmt.Printf("saved %s\n", th.Dir)
+	}
+
+	return nil
+}
+
+func save(th *thread.Thread, a *archive.Archive, opts *cmdOpts) error {
+	th.Stamp(opts.version, time.Now())
+
+	dErr := th.Dir.Create()
+	if dErr != nil {
+		return fmt.Errorf("failed to create thread directory %s: %w", th.Dir, dErr)
+	}
+
+	if !opts.noAttachments {
+		err := th.CopyAttachments(a.MediaFile)
+		if err != nil {
+			return fmt.Errorf("failed to save thread attachment files: %w", err)
+		}
+	}
+
+	// Write the JSON file after copying attachments to record the size and digest of each file
+	fErr := th.ToJSON()
+	if fErr != nil {
+		return fmt.Errorf("failed to write thread JSON file: %w", fErr)
+	}
+
+	if opts.blobStore != nil {
+		sErr := th.StoreAttachments(opts.blobStore)
+		if sErr != nil {
+			return fmt.Errorf("failed to store thread attachment files in %s: %w", opts.blobStore.Dir(), sErr)
+		}
+	}
+
+	tErr := th.ToHTML(opts.template, opts.css)
+	if tErr != nil {
+		return fmt.Errorf("failed to write thread HTML file: %w", tErr)
+	}
+
+	return nil
+}
+
+type cmdOpts struct {
+	// Args
+	archiveDir string
+	// Flags
+	css           string
+	template      string
+	prefix        string
+	noAttachments bool
+	// Build information
+	version string
+	// Environment variables
+	path      string
+	blobStore *blobstore.Store
+}
+
+func attachOpts(cmd *flag.FlagSet, opts *cmdOpts) {
+	cmd.StringVar(&opts.css, "c", "", "optional path to CSS file")
+	cmd.StringVar(&opts.css, "css", "", "optional path to CSS file")
+
+	cmd.StringVar(&opts.template, "t", "", "optional path to template file")
+	cmd.StringVar(&opts.template, "template", "", "optional path to template file")
+
+	cmd.StringVar(&opts.prefix, "p", "", "prefix for thread names (account handle if unset)")
+	cmd.StringVar(&opts.prefix, "prefix", "", "prefix for thread names (account handle if unset)")
+
+	cmd.BoolVar(&opts.noAttachments, "no-attachments", false, "do not copy media attachments")
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	if len(args) == 0 {
+		return errs.ErrNoArgs
+	}
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	opts.archiveDir = cmd.Arg(0)
+
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
+	opts.path = envArgs.Path
+	if opts.template == "" {
+		opts.template = envArgs.Template
+	}
+	if opts.css == "" {
+		opts.css = envArgs.CSS
+	}
+	if !env.FlagPassed(cmd, "no-attachments") {
+		opts.noAttachments = envArgs.NoAttachments
+	}
+	blobStore, bErr := envArgs.BlobStore()
+	if bErr != nil {
+		return bErr
+	}
+	opts.blobStore = blobStore
+
+	if opts.path == "" {
+		return errs.ErrEmptyPath
+	}
+	if strings.TrimSpace(opts.archiveDir) == "" {
+		return errors.New("argument 'archive' cannot be empty")
+	}
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name())
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' saves every thread found in a Twitter data archive without using the API
+
+Usage:
+  %s %s [flags] <archive>
+
+Args:
+  archive  string  path to an extracted Twitter data archive
+
+Flags:
+  -c, --css             string  optional path to CSS file
+  -t, --template        string  optional path to template file
+  -p, --prefix          string  prefix for thread names (account handle if unset)
+      --no-attachments          do not copy attachments`
@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/thread"
+)
+
+const fixtureArchive = "../../pkg/archive/testdata/archive"
+
+func TestRun(t *testing.T) {
+	path := t.TempDir()
+	opts := &cmdOpts{
+		archiveDir: fixtureArchive,
+		path:       path,
+	}
+
+	// Both chains that fork from the same tweet are saved
+	expectedThreads := map[string][]string{
+		"archived_dev 1004": {"1001", "1002", "1004"},
+		"archived_dev 1005": {"1001", "1002", "1003", "1005"},
+		"archived_dev 2002": {"2001", "2002"},
+	}
+
+	if err := run(opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if len(entries) != len(expectedThreads) {
+		t.Errorf("expected %d threads, got %d", len(expectedThreads), len(entries))
+	}
+	for name, expectedIDs := range expectedThreads {
+		th, fErr := thread.FromJSON(path, name)
+		if fErr != nil {
+			t.Errorf("expected thread %s saved: %v", name, fErr)
+			continue
+		}
+		ids := []string{}
+		for _, tweet := range th.Tweets {
+			ids = append(ids, tweet.ID)
+		}
+		if len(ids) != len(expectedIDs) {
+			t.Errorf("expected thread %s to contain %v, got %v", name, expectedIDs, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != expectedIDs[i] {
+				t.Errorf("expected thread %s to contain %v, got %v", name, expectedIDs, ids)
+				break
+			}
+		}
+	}
+
+	// Media in the archive is copied and media missing from the archive is skipped
+	th, fErr := thread.FromJSON(path, "archived_dev 1005")
+	if fErr != nil {
+		t.Fatalf("unexpected error: %v", fErr)
+	}
+	photo := th.Tweets[2].Attachments[0]
+	if _, sErr := os.Stat(th.Dir.Join("attachments", photo.Name("1003"))); sErr != nil {
+		t.Errorf("expected photo copied: %v", sErr)
+	}
+	if !photo.IsRecorded() {
+		t.Error("expected digest of copied photo recorded")
+	}
+
+	// A second import skips the threads that are already saved rather than failing
+	if err := run(opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
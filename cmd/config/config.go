@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+	"github.com/dkaslovsky/thread-safe/pkg/config"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// secretKeys are configuration keys whose values are masked when listed
+var secretKeys = map[string]struct{}{
+	"token":               {},
+	"oauth.client_secret": {},
+}
+
+// isSecretKey evaluates if the value of a top level or profile configuration key is masked when listed
+func isSecretKey(key string) bool {
+	if _, secret := secretKeys[key]; secret {
+		return true
+	}
+	if strings.HasPrefix(key, "profiles.") {
+		parts := strings.SplitN(key, ".", 3)
+		_, secret := secretKeys[parts[len(parts)-1]]
+		return secret
+	}
+	return false
+}
+
+// Run executes the package's (sub)command
+func Run(appName string, args []string) error {
+	cmd := flag.NewFlagSet("config", flag.ExitOnError)
+	opts := &cmdOpts{}
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		if errors.Is(err, errs.ErrNoArgs) {
+			cmd.Usage()
+			return nil
+		}
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	if opts.action == "path" {
+		fmt.Println(config.FilePath())
+		return nil
+	}
+
+	conf, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	switch opts.action {
+	case "list":
+		for _, key := range conf.Keys() {
+			value, err := conf.Get(key)
+			if err != nil {
+				return err
+			}
+			if isSecretKey(key) && value != "" {
+				value = "********"
+			}
+			fmt.Printf("%s = %s\n", key, value)
+		}
+	case "get":
+		value, err := conf.Get(opts.key)
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+	case "set":
+		err := conf.Set(opts.key, opts.value)
+		if err != nil {
+			return err
+		}
+		return conf.Save()
+	case "unset":
+		err := conf.Unset(opts.key)
+		if err != nil {
+			return err
+		}
+		return conf.Save()
+	case "validate":
+		return validate(conf)
+	}
+
+	return nil
+}
+
+// validate checks that configured values are usable, reporting all problems found
+func validate(conf *config.Config) error {
+	problems := []string{}
+
+	envArgs, err := env.Parse()
+	if err != nil {
+		return err
+	}
+	clientOpts, cErr := envArgs.ClientOptions()
+	if cErr != nil {
+		problems = append(problems, cErr.Error())
+	} else if _, tErr := clientOpts.Transport(); tErr != nil {
+		problems = append(problems, tErr.Error())
+	}
+
+	for key, fileName := range map[string]string{"template": envArgs.Template, "css": envArgs.CSS} {
+		if fileName == "" {
+			continue
+		}
+		if _, err := os.Stat(fileName); err != nil {
+			problems = append(problems, fmt.Sprintf("%s file %s not found", key, fileName))
+		}
+	}
+	if _, err := twitter.ParseVideoQuality(envArgs.VideoQuality); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := twitter.ParseImageSize(envArgs.ImageSize); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := blobstore.ParseMode(envArgs.BlobStoreMode); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := envArgs.SecretStore(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	// Check the path used by the selected profile, which may differ from the top level path
+	if info, err := os.Stat(envArgs.Path); err != nil || !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("path %s is not a directory", envArgs.Path))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration in %s:\n  %s", config.FilePath(), strings.Join(problems, "\n  "))
+	}
+	fmt.Printf("%s is valid\n", config.FilePath())
+	return nil
+}
+
+type cmdOpts struct {
+	// Args
+	action string
+	key    string
+	value  string
+}
+
+// actionArgs maps each action to its number of arguments
+var actionArgs = map[string]int{
+	"path":     0,
+	"list":     0,
+	"get":      1,
+	"set":      2,
+	"unset":    1,
+	"validate": 0,
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	if len(args) == 0 {
+		return errs.ErrNoArgs
+	}
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	opts.action = cmd.Arg(0)
+	nArgs, ok := actionArgs[opts.action]
+	if !ok {
+		return fmt.Errorf("unknown action \"%s\"", opts.action)
+	}
+	if cmd.NArg()-1 != nArgs {
+		return fmt.Errorf("action '%s' requires %d argument(s)", opts.action, nArgs)
+	}
+	opts.key = cmd.Arg(1)
+	opts.value = cmd.Arg(2)
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name(), strings.Join(config.Keys(), "\n  "))
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' gets, sets, and validates values in the configuration file
+
+Usage:
+  %s %s <action> [key] [value]
+
+Actions:
+  path                 print the path of the configuration file
+  list                 print all keys and their values
+  get <key>            print the value of a key
+  set <key> <value>    set the value of a key
+  unset <key>          reset a key to its default value
+  validate             check the configuration for errors
+
+Keys:
+  %s`
@@ -1,95 +1,346 @@
 package env
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
+	"time"
+
+	"github.com/dkaslovsky/thread-safe/pkg/auth"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+	"github.com/dkaslovsky/thread-safe/pkg/config"
+	"github.com/dkaslovsky/thread-safe/pkg/secret"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+	"golang.org/x/term"
 )
 
 const (
 	// VarPath is the name of the environment variable indicating path for saving threads
 	VarPath = "THREAD_SAFE_PATH"
+	// VarProfile is the name of the environment variable selecting a named profile from the configuration file
+	VarProfile = "THREAD_SAFE_PROFILE"
 	// VarToken is the name of the environment variable containing the Twitter API bearer token
 	VarToken = "THREAD_SAFE_TOKEN" // nolint:gosec
+	// VarPassphrase is the name of the environment variable containing the passphrase of the encrypted secrets file
+	VarPassphrase = "THREAD_SAFE_PASSPHRASE" // nolint:gosec
+	// VarClientID is the name of the environment variable containing the OAuth 2.0 client ID used by the login command
+	VarClientID = "THREAD_SAFE_CLIENT_ID"
+	// VarClientSecret is the name of the environment variable containing the OAuth 2.0 client secret of confidential clients
+	VarClientSecret = "THREAD_SAFE_CLIENT_SECRET" // nolint:gosec
+	// VarHost is the name of the environment variable containing the base URL of the Twitter API
+	VarHost = "THREAD_SAFE_API_HOST"
+	// VarTimeout is the name of the environment variable containing the time limit for network requests
+	VarTimeout = "THREAD_SAFE_TIMEOUT"
+	// VarProxy is the name of the environment variable containing the URL of a proxy server
+	VarProxy = "THREAD_SAFE_PROXY"
+	// VarCAFile is the name of the environment variable containing the path to a certificate authority bundle
+	VarCAFile = "THREAD_SAFE_CA_FILE"
+	// VarUserAgent is the name of the environment variable containing the User-Agent for network requests
+	VarUserAgent = "THREAD_SAFE_USER_AGENT"
+	// VarTLSMinVersion is the name of the environment variable containing the minimum TLS version
+	VarTLSMinVersion = "THREAD_SAFE_TLS_MIN_VERSION"
+	// VarInsecureSkipVerify is the name of the environment variable disabling TLS certificate verification
+	VarInsecureSkipVerify = "THREAD_SAFE_INSECURE_SKIP_VERIFY"
 
-	// fileDirToken is the directory containing the file tokenFileName
+	// fileDirToken is the directory containing the file fileNameOAuthToken
 	fileDirToken = "${HOME}"
-	// fileNameToken is the name of the file in the user's $HOME directory containing the Twitter API bearer token
-	fileNameToken = ".thread-safe" // nolint:gosec
+	// fileNameOAuthToken is the name of the file in the user's $HOME directory containing the encrypted OAuth 2.0 token
+	fileNameOAuthToken = ".thread-safe-oauth" // nolint:gosec
+	// fileNameSecrets is the name of the file in the user's $HOME directory used by the encrypted file secret store
+	fileNameSecrets = ".thread-safe-secrets" // nolint:gosec
+	// fileNameOAuthKey is the name of the file in the user's configuration directory containing the OAuth 2.0 token encryption key
+	fileNameOAuthKey = "oauth.key"
+	// dirNameConfig is the name of the application's directory within the user's configuration directory
+	dirNameConfig = "thread-safe"
 )
 
-// Args holds environment variable values
+// Args holds environment variable values and their fallback values from the configuration file
 type Args struct {
-	Path  string
-	Token string
+	Profile string
+	Path    string
+	Token   string
+	// Backend of the secret store holding the token
+	SecretBackend string
+	// Defaults for command flags
+	Template      string
+	CSS           string
+	NoAttachments bool
+	VideoQuality  string
+	ImageSize     string
+	BlobStoreMode string
+	// OAuth 2.0 application settings
+	ClientID     string
+	ClientSecret string
+	// Network settings
+	Host               string
+	Timeout            string
+	Proxy              string
+	CAFile             string
+	UserAgent          string
+	TLSMinVersion      string
+	InsecureSkipVerify string
 }
 
-// Parse parses values from the environment
-func Parse() *Args {
+// profileOverride is the name of the profile selected by a command line flag, which takes precedence
+// over VarProfile
+var profileOverride string
+
+// SetProfile selects a named profile, taking precedence over the environment and the configuration file
+func SetProfile(name string) {
+	profileOverride = name
+}
+
+// Parse parses values from the environment, falling back to values read from the configuration file
+// using the selected profile
+func Parse() (*Args, error) {
+	fileConf, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if fileConf.HasToken() && config.ReadableByOthers(config.FilePath()) {
+		fmt.Fprintf(os.Stderr, "warning: %s contains a token and is readable by other users, run \"chmod 600 %s\" or move the token with the token command\n",
+			config.FilePath(), config.FilePath())
+	}
+
+	profile := lookup(VarProfile, "")
+	if profileOverride != "" {
+		profile = profileOverride
+	}
+	conf, pErr := fileConf.WithProfile(profile)
+	if pErr != nil {
+		return nil, pErr
+	}
+
 	path := "."
+	if conf.Path != "" {
+		path = os.ExpandEnv(conf.Path)
+	}
 	if p, ok := os.LookupEnv(VarPath); ok {
 		path = p
 	}
 
-	token := ""
-	if t, ok := os.LookupEnv(VarToken); ok {
-		token = t
-	} else {
-		token = readTokenFile()
+	insecureSkipVerify := ""
+	if conf.Network.InsecureSkipVerify {
+		insecureSkipVerify = strconv.FormatBool(conf.Network.InsecureSkipVerify)
 	}
 
 	return &Args{
-		Path:  path,
-		Token: token,
+		Profile:            conf.Profile,
+		Path:               path,
+		Token:              lookup(VarToken, conf.Token),
+		SecretBackend:      conf.SecretStore,
+		Template:           os.ExpandEnv(conf.Template),
+		CSS:                os.ExpandEnv(conf.CSS),
+		NoAttachments:      conf.Download.NoAttachments,
+		VideoQuality:       conf.Download.VideoQuality,
+		ImageSize:          conf.Download.ImageSize,
+		BlobStoreMode:      conf.Download.BlobStore,
+		ClientID:           lookup(VarClientID, conf.OAuth.ClientID),
+		ClientSecret:       lookup(VarClientSecret, conf.OAuth.ClientSecret),
+		Host:               lookup(VarHost, conf.Network.Host),
+		Timeout:            lookup(VarTimeout, conf.Network.Timeout),
+		Proxy:              lookup(VarProxy, conf.Network.Proxy),
+		CAFile:             lookup(VarCAFile, conf.Network.CAFile),
+		UserAgent:          lookup(VarUserAgent, conf.Network.UserAgent),
+		TLSMinVersion:      lookup(VarTLSMinVersion, conf.Network.TLSMinVersion),
+		InsecureSkipVerify: lookup(VarInsecureSkipVerify, insecureSkipVerify),
+	}, nil
+}
+
+// lookup returns the value of an environment variable if it is set and the fallback value otherwise
+func lookup(envVar string, fallback string) string {
+	if v, ok := os.LookupEnv(envVar); ok {
+		return v
 	}
+	return fallback
 }
 
-// Usage returns a string describing the environment variables
-func Usage() string {
-	return fmt.Sprintf(usage, VarPath, VarToken, TokenFilePath())
+// ClientOptions constructs the options used for network requests from the parsed values
+func (a *Args) ClientOptions() (twitter.Options, error) {
+	opts := twitter.Options{
+		Host:          a.Host,
+		ProxyURL:      a.Proxy,
+		CAFile:        os.ExpandEnv(a.CAFile),
+		UserAgent:     a.UserAgent,
+		TLSMinVersion: a.TLSMinVersion,
+	}
+
+	if a.Timeout != "" {
+		timeout, err := time.ParseDuration(a.Timeout)
+		if err != nil {
+			return opts, fmt.Errorf("invalid timeout %s: %w", a.Timeout, err)
+		}
+		opts.Timeout = timeout
+	}
+
+	if a.InsecureSkipVerify != "" {
+		insecure, err := strconv.ParseBool(a.InsecureSkipVerify)
+		if err != nil {
+			return opts, fmt.Errorf("invalid value %s for insecure_skip_verify: %w", a.InsecureSkipVerify, err)
+		}
+		opts.InsecureSkipVerify = insecure
+	}
+
+	return opts, nil
 }
 
-// TokenFilePath returns the unexpanded path to the file containing the Twitter API bearer token
-func TokenFilePath() string {
-	return filepath.Clean(filepath.Join(fileDirToken, fileNameToken))
+// AuthConfig constructs the OAuth 2.0 application settings from the parsed values
+func (a *Args) AuthConfig() auth.Config {
+	host := a.Host
+	if host == "" {
+		host = twitter.DefaultHost
+	}
+	return auth.Config{
+		ClientID:     a.ClientID,
+		ClientSecret: a.ClientSecret,
+		Host:         host,
+		RedirectPort: auth.DefaultRedirectPort,
+	}
 }
 
-// tokenFilePathExpanded returns the full path to the file containing the Twitter API bearer token
-func tokenFilePathExpanded() string {
-	return filepath.Clean(filepath.Join(os.ExpandEnv(fileDirToken), fileNameToken))
+// OAuthStore returns the Store holding the token saved by the login command for the selected profile
+func (a *Args) OAuthStore() *auth.Store {
+	keyDir, err := os.UserConfigDir()
+	if err != nil {
+		keyDir = os.ExpandEnv(fileDirToken)
+	}
+
+	tokenFileName := fileNameOAuthToken
+	if a.Profile != "" {
+		tokenFileName = fmt.Sprintf("%s-%s", fileNameOAuthToken, a.Profile)
+	}
+
+	return auth.NewStore(
+		filepath.Join(os.ExpandEnv(fileDirToken), tokenFileName),
+		filepath.Join(keyDir, dirNameConfig, fileNameOAuthKey),
+	)
 }
 
-func readTokenFile() string {
-	file, err := os.Open(tokenFilePathExpanded())
+// BlobStore returns the Store deduplicating attachment files across all threads saved under the top
+// level path, or nil if it is disabled
+func (a *Args) BlobStore() (*blobstore.Store, error) {
+	mode, err := blobstore.ParseMode(a.BlobStoreMode)
 	if err != nil {
-		return ""
-	}
-	defer func() {
-		_ = file.Close()
-	}()
-
-	var token string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineParts := strings.Split(line, "=")
-		if len(lineParts) != 2 {
-			continue
-		}
-		if strings.ToLower(strings.TrimSpace(lineParts[0])) == "token" {
-			token = strings.TrimSpace(lineParts[1])
-			break
-		}
+		return nil, err
 	}
-	if err := scanner.Err(); err != nil {
-		return ""
+	if mode == blobstore.ModeOff {
+		return nil, nil
 	}
+	store, oErr := blobstore.Open(a.Path, mode)
+	if oErr != nil {
+		return nil, fmt.Errorf("failed to open blob store: %w", oErr)
+	}
+	return store, nil
+}
 
-	return token
+// SecretStore returns the Store used by the token command, which prompts for the passphrase of the
+// encrypted file backend if it is not set by VarPassphrase
+func (a *Args) SecretStore() (secret.Store, error) {
+	return secret.New(a.SecretBackend, filepath.Join(os.ExpandEnv(fileDirToken), fileNameSecrets), passphrase)
+}
+
+// SecretKey returns the key of the selected profile's token in the secret store
+func (a *Args) SecretKey() string {
+	if a.Profile == "" {
+		return "token"
+	}
+	return fmt.Sprintf("token-%s", a.Profile)
+}
+
+// StoredToken returns the selected profile's token from the secret store, or an empty string if the
+// secret store does not contain a token
+func (a *Args) StoredToken() (string, error) {
+	store, err := a.SecretStore()
+	if err != nil {
+		return "", err
+	}
+	token, gErr := store.Get(a.SecretKey())
+	if errors.Is(gErr, secret.ErrNotFound) {
+		return "", nil
+	}
+	if gErr != nil {
+		return "", fmt.Errorf("failed to read token from %s: %w", store.Name(), gErr)
+	}
+	return token, nil
+}
+
+// passphrase returns the passphrase of the encrypted secrets file from VarPassphrase or by prompting
+// on the terminal, prompting twice if confirm is true
+func passphrase(confirm bool) ([]byte, error) {
+	if p, ok := os.LookupEnv(VarPassphrase); ok {
+		return []byte(p), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("passphrase for the secrets file must be set by the environment variable %s when not running in a terminal", VarPassphrase)
+	}
+
+	fmt.Print("Passphrase for secrets file: ")
+	p, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if !confirm {
+		return p, nil
+	}
+
+	fmt.Print("Confirm passphrase: ")
+	c, cErr := term.ReadPassword(fd)
+	fmt.Println()
+	if cErr != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", cErr)
+	}
+	if string(p) != string(c) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+// Usage returns a string describing the environment variables
+func Usage() string {
+	return fmt.Sprintf(usage, VarProfile, VarPath, VarToken, VarPassphrase, VarClientID, VarClientSecret, VarHost, VarTimeout, VarProxy,
+		VarCAFile, VarUserAgent, VarTLSMinVersion, VarInsecureSkipVerify, config.VarConfig, ConfigFilePath())
+}
+
+// FlagPassed evaluates if any of the named flags was explicitly passed to a command, allowing flag
+// values to take precedence over the configuration file
+func FlagPassed(cmd *flag.FlagSet, names ...string) bool {
+	passed := false
+	cmd.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				passed = true
+			}
+		}
+	})
+	return passed
+}
+
+// ConfigFilePath returns the path to the configuration file
+func ConfigFilePath() string {
+	return config.FilePath()
 }
 
 var usage = `Environment Variables:
-  %s	top level path for thread files (current directory if unset)
-  %s	bearer token for Twitter API (overrides value read from "%s" if set)`
+  %-33s name of the profile to use from the configuration file
+  %-33s top level path for thread files (current directory if unset)
+  %-33s bearer token for Twitter API
+  %-33s passphrase of the encrypted file used to store the token when no OS secret store is available
+  %-33s OAuth 2.0 client ID used by the login command
+  %-33s OAuth 2.0 client secret (only for confidential clients)
+  %-33s base URL of the Twitter API (https://api.twitter.com if unset)
+  %-33s time limit for each network request, e.g. 30s (no limit if unset)
+  %-33s URL of a proxy server (standard proxy variables are used if unset)
+  %-33s path to a PEM file of additional certificate authorities to trust
+  %-33s User-Agent header for network requests
+  %-33s minimum TLS version for network requests (1.0, 1.1, 1.2, or 1.3)
+  %-33s disable verification of TLS certificates if true
+  %-33s path to the configuration file
+
+Environment variables override values set in the configuration file "%s"`
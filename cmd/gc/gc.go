@@ -0,0 +1,96 @@
+package gc
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+)
+
+// Run executes the package's (sub)command
+func Run(appName string, args []string) error {
+	cmd := flag.NewFlagSet("gc", flag.ExitOnError)
+	opts := &cmdOpts{}
+	attachOpts(cmd, opts)
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	// Blobs are removed the same way regardless of how they are linked, so the store can be collected
+	// even after it has been disabled
+	store, err := blobstore.Open(opts.path, blobstore.ModeOff)
+	if err != nil {
+		return fmt.Errorf("failed to open blob store: %w", err)
+	}
+
+	result, gErr := store.GC(opts.dryRun)
+	if gErr != nil {
+		return fmt.Errorf("failed to collect unreferenced blobs in %s: %w", store.Dir(), gErr)
+	}
+
+	action := "removed"
+	if opts.dryRun {
+		action = "would remove"
+	}
+	fmt.Printf("%s %d unreferenced blob(s) freeing %d bytes, %d blob(s) still referenced\n",
+		action, result.Removed, result.Freed, result.Kept)
+	return nil
+}
+
+type cmdOpts struct {
+	// Flags
+	dryRun bool
+	// Environment variables
+	path string
+}
+
+func attachOpts(cmd *flag.FlagSet, opts *cmdOpts) {
+	cmd.BoolVar(&opts.dryRun, "n", false, "report unreferenced blobs without removing them")
+	cmd.BoolVar(&opts.dryRun, "dry-run", false, "report unreferenced blobs without removing them")
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
+	opts.path = envArgs.Path
+
+	if opts.path == "" {
+		return errs.ErrEmptyPath
+	}
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name())
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' removes attachment files from the blob store that are no longer used by any saved thread
+
+Usage:
+  %s %s [flags]
+
+Attachments are stored once in the blob store when the blob_store key of the configuration file's
+[download] section is set to hardlink or symlink. Blobs whose threads have been deleted or whose
+attachments have been downloaded again are removed by this command.
+
+Flags:
+  -n, --dry-run  report unreferenced blobs without removing them`
@@ -0,0 +1,129 @@
+package login
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/pkg/auth"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// Run executes the package's (sub)command
+func Run(appName string, args []string) error {
+	cmd := flag.NewFlagSet("login", flag.ExitOnError)
+	opts := &cmdOpts{}
+	attachOpts(cmd, opts)
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	store := opts.oauthStore
+
+	if opts.logout {
+		err := store.Delete()
+		if err != nil {
+			return fmt.Errorf("failed to delete saved login: %w", err)
+		}
+		fmt.Println("Logged out")
+		return nil
+	}
+
+	httpClient, err := opts.clientOpts.NewHTTPClient()
+	if err != nil {
+		return fmt.Errorf("invalid network settings: %w", err)
+	}
+
+	tok, lErr := auth.Login(opts.authConf, httpClient, func(authURL string) {
+		fmt.Printf("Open the following URL in a browser to authorize access to your account:\n\n%s\n\n", authURL)
+		fmt.Printf("Waiting for authorization at %s ...\n", opts.authConf.RedirectURL())
+	})
+	if lErr != nil {
+		return fmt.Errorf("login failed: %w", lErr)
+	}
+
+	sErr := store.Save(tok)
+	if sErr != nil {
+		return fmt.Errorf("failed to save login: %w", sErr)
+	}
+
+	fmt.Println("Login successful")
+	return nil
+}
+
+type cmdOpts struct {
+	// Flags
+	port   int
+	logout bool
+	// Environment variables
+	authConf   auth.Config
+	clientOpts twitter.Options
+	oauthStore *auth.Store
+}
+
+func attachOpts(cmd *flag.FlagSet, opts *cmdOpts) {
+	cmd.IntVar(&opts.port, "p", auth.DefaultRedirectPort, "port of the loopback redirect URI")
+	cmd.IntVar(&opts.port, "port", auth.DefaultRedirectPort, "port of the loopback redirect URI")
+
+	cmd.BoolVar(&opts.logout, "logout", false, "delete the saved login")
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
+	clientOpts, cErr := envArgs.ClientOptions()
+	if cErr != nil {
+		return cErr
+	}
+	opts.clientOpts = clientOpts
+	opts.authConf = envArgs.AuthConfig()
+	opts.authConf.RedirectPort = opts.port
+	opts.oauthStore = envArgs.OAuthStore()
+
+	if opts.logout {
+		return nil
+	}
+	if opts.authConf.ClientID == "" {
+		return fmt.Errorf("client ID must be specified in %s or by the environment variable %s", env.ConfigFilePath(), env.VarClientID)
+	}
+	if opts.port <= 0 || opts.port > 65535 {
+		return errors.New("flag 'port' must be a valid port number")
+	}
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name(), auth.DefaultRedirectPort)
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' authorizes access to the Twitter API on behalf of your account
+
+Usage:
+  %s %s [flags]
+
+The OAuth 2.0 Authorization Code flow with PKCE is used to obtain a token that can read
+protected accounts followed by your account. The application's client ID is required and
+its redirect URI must be registered as http://127.0.0.1:<port>/callback. Once saved, the
+token is used instead of the bearer token and is refreshed automatically.
+
+Flags:
+  -p, --port    int  port of the loopback redirect URI (default %d)
+      --logout       delete the saved login`
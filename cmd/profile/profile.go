@@ -0,0 +1,187 @@
+package profile
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/config"
+	"github.com/dkaslovsky/thread-safe/pkg/secret"
+)
+
+// Run executes the package's (sub)command
+func Run(appName string, args []string) error {
+	cmd := flag.NewFlagSet("profile", flag.ExitOnError)
+	opts := &cmdOpts{}
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		if errors.Is(err, errs.ErrNoArgs) {
+			cmd.Usage()
+			return nil
+		}
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	conf, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	switch opts.action {
+	case "list":
+		envArgs, err := env.Parse()
+		if err != nil {
+			return err
+		}
+		for _, name := range conf.ProfileNames() {
+			marker := " "
+			if name == envArgs.Profile {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+	case "show":
+		name := opts.name
+		if name == "" {
+			envArgs, err := env.Parse()
+			if err != nil {
+				return err
+			}
+			name = envArgs.Profile
+		}
+		if name == "" {
+			return errors.New("no profile is selected")
+		}
+		profile, ok := conf.Profiles[name]
+		if !ok {
+			return fmt.Errorf("profile %s not found", name)
+		}
+		token := ""
+		if profile.Token != "" {
+			token = "********"
+		}
+		fmt.Printf("name = %s\npath = %s\ntoken = %s\ntemplate = %s\ncss = %s\n",
+			name, profile.Path, token, profile.Template, profile.CSS)
+	case "use":
+		if _, ok := conf.Profiles[opts.name]; !ok {
+			return fmt.Errorf("profile %s not found", opts.name)
+		}
+		conf.Profile = opts.name
+		return conf.Save()
+	case "create":
+		if _, ok := conf.Profiles[opts.name]; ok {
+			return fmt.Errorf("profile %s already exists", opts.name)
+		}
+		if conf.Profiles == nil {
+			conf.Profiles = map[string]config.Profile{}
+		}
+		conf.Profiles[opts.name] = config.Profile{}
+		return conf.Save()
+	case "delete":
+		if _, ok := conf.Profiles[opts.name]; !ok {
+			return fmt.Errorf("profile %s not found", opts.name)
+		}
+		deleteCredentials(conf, opts.name)
+		delete(conf.Profiles, opts.name)
+		if conf.Profile == opts.name {
+			conf.Profile = ""
+		}
+		return conf.Save()
+	}
+
+	return nil
+}
+
+// deleteCredentials removes the login saved by the login command and the token saved by the token
+// command for a profile, warning where any credentials that could not be removed remain
+func deleteCredentials(conf *config.Config, name string) {
+	envArgs := &env.Args{
+		Profile:       name,
+		SecretBackend: conf.SecretStore,
+	}
+
+	oErr := envArgs.OAuthStore().Delete()
+	if oErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to delete the saved login of profile %s: %v\n", name, oErr)
+	}
+
+	store, err := envArgs.SecretStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to open the secret store, the token of profile %s may remain under the key %s: %v\n",
+			name, envArgs.SecretKey(), err)
+		return
+	}
+	dErr := store.Delete(envArgs.SecretKey())
+	if dErr != nil && !errors.Is(dErr, secret.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "warning: failed to delete the token of profile %s from %s, it remains under the key %s: %v\n",
+			name, store.Name(), envArgs.SecretKey(), dErr)
+	}
+}
+
+type cmdOpts struct {
+	// Args
+	action string
+	name   string
+}
+
+// actionArgs maps each action to its minimum and maximum number of arguments
+var actionArgs = map[string][2]int{
+	"list":   {0, 0},
+	"show":   {0, 1},
+	"use":    {1, 1},
+	"create": {1, 1},
+	"delete": {1, 1},
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	if len(args) == 0 {
+		return errs.ErrNoArgs
+	}
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	opts.action = cmd.Arg(0)
+	nArgs, ok := actionArgs[opts.action]
+	if !ok {
+		return fmt.Errorf("unknown action \"%s\"", opts.action)
+	}
+	if n := cmd.NArg() - 1; n < nArgs[0] || n > nArgs[1] {
+		return fmt.Errorf("invalid number of arguments for action '%s'", opts.action)
+	}
+	opts.name = cmd.Arg(1)
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name(), appName)
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' lists and switches between named profiles in the configuration file
+
+Usage:
+  %s %s <action> [name]
+
+Actions:
+  list             list profiles, marking the selected profile with *
+  show [name]      print the values of a profile (the selected profile if no name is given)
+  use <name>       set the profile used by default
+  create <name>    add an empty profile
+  delete <name>    remove a profile along with its saved login and token
+
+Each profile may set its own path, token, template, and css, which take precedence over the
+top level values of the configuration file. Set profile values with the config command, e.g.,
+  %s config set profiles.<name>.token <token>`
@@ -0,0 +1,178 @@
+package redownload
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+	"github.com/dkaslovsky/thread-safe/pkg/thread"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// Run executes the package's (sub)command
+func Run(appName string, args []string) error {
+	cmd := flag.NewFlagSet("redownload", flag.ExitOnError)
+	opts := &cmdOpts{}
+	attachOpts(cmd, opts)
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		if errors.Is(err, errs.ErrNoArgs) {
+			cmd.Usage()
+			return nil
+		}
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	th, err := thread.FromJSON(opts.path, opts.name)
+	if err != nil {
+		return fmt.Errorf("failed to load thread from file: %w", err)
+	}
+
+	httpClient, cErr := opts.clientOpts.NewHTTPClient()
+	if cErr != nil {
+		return fmt.Errorf("invalid network settings: %w", cErr)
+	}
+
+	th.SetVideoQuality(opts.videoQuality)
+
+	dErr := th.DownloadAttachments(httpClient, opts.imageSize)
+	if dErr != nil {
+		return fmt.Errorf("failed to save thread attachment files: %w", dErr)
+	}
+	aErr := th.DownloadAvatar(httpClient)
+	if aErr != nil {
+		return fmt.Errorf("failed to save thread author's profile image: %w", aErr)
+	}
+
+	if opts.blobStore != nil {
+		sErr := th.StoreAttachments(opts.blobStore)
+		if sErr != nil {
+			return fmt.Errorf("failed to store thread attachment files in %s: %w", opts.blobStore.Dir(), sErr)
+		}
+	}
+
+	// Record the downloaded video variants and image sizes only once all attachments have been saved
+	fErr := th.ToJSON()
+	if fErr != nil {
+		return fmt.Errorf("failed to write thread JSON file: %w", fErr)
+	}
+
+	hErr := th.ToHTML(opts.template, opts.css)
+	if hErr != nil {
+		return fmt.Errorf("failed to write thread HTML file: %w", hErr)
+	}
+
+	return nil
+}
+
+type cmdOpts struct {
+	// Args
+	name string
+	// Flags
+	css          string
+	template     string
+	videoQuality twitter.VideoQuality
+	imageSize    twitter.ImageSize
+	// Environment variables
+	path       string
+	clientOpts twitter.Options
+	blobStore  *blobstore.Store
+}
+
+func attachOpts(cmd *flag.FlagSet, opts *cmdOpts) {
+	cmd.StringVar(&opts.css, "c", "", "optional path to CSS file")
+	cmd.StringVar(&opts.css, "css", "", "optional path to CSS file")
+
+	cmd.StringVar(&opts.template, "t", "", "optional path to template file")
+	cmd.StringVar(&opts.template, "template", "", "optional path to template file")
+
+	cmd.Var(&opts.videoQuality, "video-quality", "video variant to download: best, worst, or a maximum bit rate")
+	cmd.Var(&opts.imageSize, "image-size", "image size to download: default, orig, large, medium, small, or thumb")
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	if len(args) == 0 {
+		return errs.ErrNoArgs
+	}
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	opts.name = cmd.Arg(0)
+
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
+	opts.path = envArgs.Path
+	if opts.template == "" {
+		opts.template = envArgs.Template
+	}
+	if opts.css == "" {
+		opts.css = envArgs.CSS
+	}
+	if !env.FlagPassed(cmd, "video-quality") {
+		videoQuality, vErr := twitter.ParseVideoQuality(envArgs.VideoQuality)
+		if vErr != nil {
+			return vErr
+		}
+		opts.videoQuality = videoQuality
+	}
+	if !env.FlagPassed(cmd, "image-size") {
+		imageSize, iErr := twitter.ParseImageSize(envArgs.ImageSize)
+		if iErr != nil {
+			return iErr
+		}
+		opts.imageSize = imageSize
+	}
+
+	clientOpts, cErr := envArgs.ClientOptions()
+	if cErr != nil {
+		return cErr
+	}
+	opts.clientOpts = clientOpts
+	blobStore, bErr := envArgs.BlobStore()
+	if bErr != nil {
+		return bErr
+	}
+	opts.blobStore = blobStore
+
+	if opts.path == "" {
+		return errs.ErrEmptyPath
+	}
+	if strings.TrimSpace(opts.name) == "" {
+		return errors.New("argument 'name' cannot be empty")
+	}
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name())
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' downloads the attachments of a previously saved thread again, such as to change the quality of its videos
+
+Usage:
+  %s %s [flags] <name>
+
+Args:
+  name  string  name given to the thread
+
+Flags:
+  -c, --css            string  optional path to CSS file
+  -t, --template       string  optional path to template file
+      --video-quality  string  video variant to download: best (default), worst, or a maximum bit rate in bits per second
+      --image-size     string  image size to download: default, orig, large, medium, small, or thumb`
@@ -72,8 +72,17 @@ func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
 	}
 	opts.name = cmd.Arg(0)
 
-	envArgs := env.Parse()
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
 	opts.path = envArgs.Path
+	if opts.template == "" {
+		opts.template = envArgs.Template
+	}
+	if opts.css == "" {
+		opts.css = envArgs.CSS
+	}
 
 	if opts.path == "" {
 		return errs.ErrEmptyPath
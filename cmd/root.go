@@ -3,11 +3,18 @@ package cmd
 import (
 	"flag"
 	"fmt"
-	"os"
 
+	"github.com/dkaslovsky/thread-safe/cmd/archive"
+	"github.com/dkaslovsky/thread-safe/cmd/config"
 	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/gc"
+	"github.com/dkaslovsky/thread-safe/cmd/login"
+	"github.com/dkaslovsky/thread-safe/cmd/profile"
+	"github.com/dkaslovsky/thread-safe/cmd/redownload"
 	"github.com/dkaslovsky/thread-safe/cmd/regen"
 	"github.com/dkaslovsky/thread-safe/cmd/save"
+	"github.com/dkaslovsky/thread-safe/cmd/token"
+	"github.com/dkaslovsky/thread-safe/cmd/verify"
 )
 
 // Run executes the top level command
@@ -16,6 +23,9 @@ func Run(name string, version string) error {
 	flag.BoolVar(&versionFlag, "v", false, fmt.Sprintf("version for %s", name))
 	flag.BoolVar(&versionFlag, "version", false, fmt.Sprintf("version for %s", name))
 
+	var profileFlag string
+	flag.StringVar(&profileFlag, "profile", "", "name of the profile to use from the configuration file")
+
 	setUsage(name)
 	flag.Parse()
 
@@ -29,13 +39,31 @@ func Run(name string, version string) error {
 		return nil
 	}
 
-	subCmd, args := flag.Arg(0), os.Args[2:]
+	env.SetProfile(profileFlag)
+
+	subCmd, args := flag.Arg(0), flag.Args()[1:]
 
 	switch subCmd {
 	case "save":
-		return save.Run(name, args)
+		return save.Run(name, version, args)
 	case "regen":
 		return regen.Run(name, args)
+	case "redownload":
+		return redownload.Run(name, args)
+	case "verify":
+		return verify.Run(name, args)
+	case "gc":
+		return gc.Run(name, args)
+	case "import":
+		return archive.Run(name, version, args)
+	case "login":
+		return login.Run(name, args)
+	case "config":
+		return config.Run(name, args)
+	case "profile":
+		return profile.Run(name, args)
+	case "token":
+		return token.Run(name, args)
 	case "version":
 		printVersion(name, version)
 	case "help":
@@ -68,12 +96,21 @@ Usage:
   %s [command]
 
 Available Commands:
-  save    saves thread content and generates a local html file
-  regen   regenerates an html file from a previously saved thread
+  save        saves thread content and generates a local html file
+  regen       regenerates an html file from a previously saved thread
+  redownload  downloads the attachments of a previously saved thread again
+  verify      checks saved threads for missing or damaged files
+  gc          removes attachment files that are no longer used by any saved thread
+  import      saves threads from a Twitter data archive without using the API
+  login       authorizes access to the Twitter API on behalf of your account
+  config      gets, sets, and validates values in the configuration file
+  profile     lists and switches between named profiles in the configuration file
+  token       saves the bearer token in the OS secret store or an encrypted file
 
 Flags:
-  -h, --help	 help for %s
-  -v, --version	 version for %s
+  -h, --help              help for %s
+  -v, --version           version for %s
+      --profile  string   name of the profile to use from the configuration file
 
 %s
 
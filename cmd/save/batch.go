@@ -0,0 +1,173 @@
+package save
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dkaslovsky/thread-safe/pkg/thread"
+)
+
+// batchEntry is a thread listed in a batch file
+type batchEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	tweetID string
+}
+
+// runBatch saves each thread listed in the batch file using a shared client, skipping threads that are
+// already saved and continuing past threads that fail to save, and reports the results once all threads
+// are processed. An error is returned only if a thread failed to save.
+func runBatch(opts *cmdOpts) error {
+	entries, err := readBatchFile(opts.batch)
+	if err != nil {
+		return fmt.Errorf("failed to read batch file %s: %w", opts.batch, err)
+	}
+
+	httpClient, client, cErr := newClients(opts)
+	if cErr != nil {
+		return cErr
+	}
+
+	saved, skipped := 0, 0
+	failed := []string{}
+	for _, entry := range entries {
+		th := thread.New(opts.path, entry.Name)
+		if th.Dir.Exists() {
+			fmt.Printf("skipped %s: already exists\n", th.Dir)
+			skipped++
+			continue
+		}
+
+		sErr := saveThread(th, entry.tweetID, opts, client, httpClient)
+		if sErr != nil {
+			fmt.Printf("failed %s: %v\n", entry.Name, sErr)
+			failed = append(failed, entry.Name)
+			// Remove the partially saved thread so that it is retried rather than skipped by the next run,
+			// along with its references in the blob store so that the index only lists saved files
+			if rErr := th.Dir.Remove(); rErr != nil {
+				fmt.Printf("failed to remove %s: %v\n", th.Dir, rErr)
+			} else if opts.blobStore != nil {
+				if fErr := opts.blobStore.Forget(th.Dir.String()); fErr != nil {
+					fmt.Printf("failed to remove references to %s from %s: %v\n", th.Dir, opts.blobStore.Dir(), fErr)
+				}
+			}
+			continue
+		}
+		fmt.Printf("saved %s\n", th.Dir)
+		saved++
+	}
+
+	fmt.Printf("\n%d saved, %d skipped, %d failed\n", saved, skipped, len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to save %d of %d threads: %s", len(failed), len(entries), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// readBatchFile reads the threads listed in a batch file, which is a JSON array of objects with "name"
+// and "url" keys if its extension is .json, CSV records of a name and URL, with an optional header, if
+// its extension is .csv, and otherwise lines of a name and URL separated by a tab. Blank lines and lines
+// starting with "#" are ignored in CSV and text files.
+func readBatchFile(fileName string) ([]*batchEntry, error) {
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var entries []*batchEntry
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		entries, err = readBatchJSON(f)
+	case ".csv":
+		entries, err = readBatchCSV(f)
+	default:
+		entries, err = readBatchText(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no threads listed")
+	}
+	names := map[string]struct{}{}
+	for _, entry := range entries {
+		entry.Name = strings.TrimSpace(entry.Name)
+		entry.URL = strings.TrimSpace(entry.URL)
+		if entry.Name == "" || entry.URL == "" {
+			return nil, fmt.Errorf("name and URL cannot be empty: %q %q", entry.Name, entry.URL)
+		}
+		if _, ok := names[entry.Name]; ok {
+			return nil, fmt.Errorf("name %s is listed more than once", entry.Name)
+		}
+		names[entry.Name] = struct{}{}
+
+		tweetID, tErr := parseTweetID(entry.URL)
+		if tErr != nil {
+			return nil, tErr
+		}
+		entry.tweetID = tweetID
+	}
+	return entries, nil
+}
+
+func readBatchJSON(r io.Reader) ([]*batchEntry, error) {
+	entries := []*batchEntry{}
+	err := json.NewDecoder(r).Decode(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return entries, nil
+}
+
+func readBatchCSV(r io.Reader) ([]*batchEntry, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+
+	entries := []*batchEntry{}
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(record[0], "name") && strings.EqualFold(record[1], "url") {
+			continue
+		}
+		entries = append(entries, &batchEntry{Name: record[0], URL: record[1]})
+	}
+	return entries, nil
+}
+
+func readBatchText(r io.Reader) ([]*batchEntry, error) {
+	entries := []*batchEntry{}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a name and URL separated by a tab", lineNum)
+		}
+		entries = append(entries, &batchEntry{Name: fields[0], URL: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,235 @@
+package save
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/auth"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+	"github.com/dkaslovsky/thread-safe/pkg/thread"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+func TestReadBatchFile(t *testing.T) {
+	tests := map[string]struct {
+		fileName        string
+		contents        string
+		expectedEntries []batchEntry
+		expectedErr     bool
+	}{
+		"text": {
+			fileName: "threads.txt",
+			contents: "# saved threads\n\n" +
+				"Nathan MacKinnon 2018\thttps://twitter.com/Avalanche/status/969990907490484225\n" +
+				"  features\t1700000000000000007  \n",
+			expectedEntries: []batchEntry{
+				{Name: "Nathan MacKinnon 2018", URL: "https://twitter.com/Avalanche/status/969990907490484225", tweetID: "969990907490484225"},
+				{Name: "features", URL: "1700000000000000007", tweetID: "1700000000000000007"},
+			},
+		},
+		"text without tab": {
+			fileName:    "threads.tsv",
+			contents:    "features 1700000000000000007\n",
+			expectedErr: true,
+		},
+		"text with extra field": {
+			fileName:    "threads.tsv",
+			contents:    "features\t1700000000000000007\textra\n",
+			expectedErr: true,
+		},
+		"CSV with header": {
+			fileName: "threads.csv",
+			contents: "name,url\n" +
+				"# saved threads\n" +
+				"\"MacKinnon, 2018\", https://twitter.com/Avalanche/status/969990907490484225\n",
+			expectedEntries: []batchEntry{
+				{Name: "MacKinnon, 2018", URL: "https://twitter.com/Avalanche/status/969990907490484225", tweetID: "969990907490484225"},
+			},
+		},
+		"CSV without header": {
+			fileName: "THREADS.CSV",
+			contents: "features,1700000000000000007\n",
+			expectedEntries: []batchEntry{
+				{Name: "features", URL: "1700000000000000007", tweetID: "1700000000000000007"},
+			},
+		},
+		"CSV with missing field": {
+			fileName:    "threads.csv",
+			contents:    "features\n",
+			expectedErr: true,
+		},
+		"JSON": {
+			fileName: "threads.json",
+			contents: `[
+				{"name": "Nathan MacKinnon 2018", "url": "https://twitter.com/Avalanche/status/969990907490484225"},
+				{"name": "features", "url": "1700000000000000007"}
+			]`,
+			expectedEntries: []batchEntry{
+				{Name: "Nathan MacKinnon 2018", URL: "https://twitter.com/Avalanche/status/969990907490484225", tweetID: "969990907490484225"},
+				{Name: "features", URL: "1700000000000000007", tweetID: "1700000000000000007"},
+			},
+		},
+		"invalid JSON": {
+			fileName:    "threads.json",
+			contents:    `{"name": "features", "url": "1700000000000000007"}`,
+			expectedErr: true,
+		},
+		"empty name": {
+			fileName:    "threads.json",
+			contents:    `[{"name": " ", "url": "1700000000000000007"}]`,
+			expectedErr: true,
+		},
+		"empty URL": {
+			fileName:    "threads.csv",
+			contents:    "features,\n",
+			expectedErr: true,
+		},
+		"duplicate name": {
+			fileName:    "threads.txt",
+			contents:    "features\t1700000000000000007\nfeatures\t1700000000000000009\n",
+			expectedErr: true,
+		},
+		"no threads": {
+			fileName:    "threads.txt",
+			contents:    "# nothing to save\n",
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), test.fileName)
+			if err := os.WriteFile(fileName, []byte(test.contents), 0o600); err != nil {
+				t.Fatalf("failed to write %s: %v", fileName, err)
+			}
+
+			entries, err := readBatchFile(fileName)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got entries %+v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != len(test.expectedEntries) {
+				t.Fatalf("expected %d entries, got %d", len(test.expectedEntries), len(entries))
+			}
+			for i, entry := range entries {
+				if *entry != test.expectedEntries[i] {
+					t.Errorf("expected entry %+v, got %+v", test.expectedEntries[i], *entry)
+				}
+			}
+		})
+	}
+}
+
+func TestReadBatchFileMissing(t *testing.T) {
+	_, err := readBatchFile(filepath.Join(t.TempDir(), "threads.txt"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	server := twittertest.NewServer(fixturesFeatures)
+	defer server.Close()
+
+	dir := t.TempDir()
+	batchFile := filepath.Join(dir, "threads.txt")
+	contents := "saved\t1700000000000000007\n" +
+		"existing\t1700000000000000003\n" +
+		"unknown\t1\n"
+	if err := os.WriteFile(batchFile, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", batchFile, err)
+	}
+
+	path := filepath.Join(dir, "threads")
+	if err := os.MkdirAll(filepath.Join(path, "existing"), 0o750); err != nil {
+		t.Fatalf("failed to create existing thread: %v", err)
+	}
+
+	opts := &cmdOpts{
+		noAttachments: true,
+		maxTweets:     thread.DefaultMaxLength,
+		batch:         batchFile,
+		path:          path,
+		token:         "token",
+		clientOpts:    twitter.Options{Host: server.URL},
+		oauthStore:    auth.NewStore(filepath.Join(dir, "token"), filepath.Join(dir, "key")),
+	}
+
+	err := runBatch(opts)
+	if err == nil || !strings.Contains(err.Error(), "failed to save 1 of 3 threads: unknown") {
+		t.Errorf("expected error reporting the failed thread, got %v", err)
+	}
+
+	if _, fErr := thread.FromJSON(path, "saved"); fErr != nil {
+		t.Errorf("expected thread saved: %v", fErr)
+	}
+	if _, fErr := thread.FromJSON(path, "existing"); fErr == nil {
+		t.Error("expected existing thread to be skipped")
+	}
+	// A thread that fails to save is removed so that the next run retries it
+	if _, sErr := os.Stat(filepath.Join(path, "unknown")); !os.IsNotExist(sErr) {
+		t.Errorf("expected failed thread to be removed, got %v", sErr)
+	}
+}
+
+func TestRunBatchForgetsFailedThreadBlobs(t *testing.T) {
+	server := twittertest.NewServer(fixturesNathanMacKinnon)
+	defer server.Close()
+
+	dir := t.TempDir()
+	batchFile := filepath.Join(dir, "threads.txt")
+	if err := os.WriteFile(batchFile, []byte("failed\t969990907490484225\n"), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", batchFile, err)
+	}
+
+	path := filepath.Join(dir, "threads")
+	store, err := blobstore.Open(path, blobstore.ModeHardlink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := &cmdOpts{
+		maxTweets: thread.DefaultMaxLength,
+		batch:     batchFile,
+		path:      path,
+		// The thread fails to save only after its attachments are stored, as the template is missing
+		template:   filepath.Join(dir, "missing.html"),
+		token:      "token",
+		clientOpts: twitter.Options{Host: server.URL},
+		oauthStore: auth.NewStore(filepath.Join(dir, "token"), filepath.Join(dir, "key")),
+		blobStore:  store,
+	}
+
+	if rErr := runBatch(opts); rErr == nil {
+		t.Fatal("expected error")
+	}
+
+	b, rErr := os.ReadFile(filepath.Join(store.Dir(), "index.json"))
+	if rErr != nil {
+		t.Fatalf("expected blob store index: %v", rErr)
+	}
+	index := struct {
+		Blobs map[string]struct {
+			Refs []string `json:"refs"`
+		} `json:"blobs"`
+	}{}
+	if jErr := json.Unmarshal(b, &index); jErr != nil {
+		t.Fatalf("failed to parse blob store index: %v", jErr)
+	}
+	if len(index.Blobs) == 0 {
+		t.Fatal("expected attachments stored before the thread failed")
+	}
+	for digest, e := range index.Blobs {
+		if len(e.Refs) > 0 {
+			t.Errorf("expected no references to blob %s, got %v", digest, e.Refs)
+		}
+	}
+}
@@ -4,19 +4,25 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dkaslovsky/thread-safe/cmd/env"
 	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/auth"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
 	"github.com/dkaslovsky/thread-safe/pkg/thread"
 	"github.com/dkaslovsky/thread-safe/pkg/twitter"
 )
 
-// Run executes the package's (sub)command
-func Run(appName string, args []string) error {
+// Run executes the package's (sub)command, recording version as the version of the application that
+// saved each thread
+func Run(appName string, version string, args []string) error {
 	cmd := flag.NewFlagSet("save", flag.ExitOnError)
-	opts := &cmdOpts{}
+	opts := &cmdOpts{version: version}
 	attachOpts(cmd, opts)
 	setUsage(appName, cmd)
 
@@ -33,16 +39,45 @@ func Run(appName string, args []string) error {
 }
 
 func run(opts *cmdOpts) error {
+	if opts.batch != "" {
+		return runBatch(opts)
+	}
+
 	th := thread.New(opts.path, opts.name)
 
 	if th.Dir.Exists() {
 		return fmt.Errorf("%s already exists, rename or delete instead of overwriting", th.Dir)
 	}
 
-	err := th.Load(twitter.NewClient(opts.token), opts.tweetID)
+	httpClient, client, err := newClients(opts)
+	if err != nil {
+		return err
+	}
+
+	return saveThread(th, opts.tweetID, opts, client, httpClient)
+}
+
+// saveThread loads the thread ending with the tweet with ID tweetID and saves it to the Thread's
+// directory, which must not exist
+func saveThread(th *thread.Thread, tweetID string, opts *cmdOpts, client twitter.Client, httpClient *http.Client) error {
+	err := th.Load(client, tweetID, newStopPolicy(opts))
 	if err != nil {
 		return fmt.Errorf("failed to parse thread: %w", err)
 	}
+	th.Stamp(opts.version, time.Now())
+
+	if opts.replyDepth != thread.RepliesNone {
+		searcher, ok := client.(twitter.ConversationSearcher)
+		if !ok {
+			return errors.New("client does not support searching for replies")
+		}
+		rErr := th.LoadReplies(searcher, opts.replyDepth)
+		if rErr != nil {
+			return fmt.Errorf("failed to load replies: %w", rErr)
+		}
+	}
+
+	th.SetVideoQuality(opts.videoQuality)
 
 	dErr := th.Dir.Create()
 	if dErr != nil {
@@ -55,10 +90,28 @@ func run(opts *cmdOpts) error {
 	}
 
 	if !opts.noAttachments {
-		err := th.DownloadAttachments()
+		err := th.DownloadAttachments(httpClient, opts.imageSize)
 		if err != nil {
 			return fmt.Errorf("failed to save thread attachment files: %w", err)
 		}
+		aErr := th.DownloadAvatar(httpClient)
+		if aErr != nil {
+			return fmt.Errorf("failed to save thread author's profile image: %w", aErr)
+		}
+
+		// Record the image sizes, file sizes, and digests of the downloaded attachments and the name of
+		// the author's profile image
+		jErr := th.ToJSON()
+		if jErr != nil {
+			return fmt.Errorf("failed to write thread JSON file: %w", jErr)
+		}
+	}
+
+	if opts.blobStore != nil {
+		sErr := th.StoreAttachments(opts.blobStore)
+		if sErr != nil {
+			return fmt.Errorf("failed to store thread attachment files in %s: %w", opts.blobStore.Dir(), sErr)
+		}
 	}
 
 	tErr := th.ToHTML(opts.template, opts.css)
@@ -69,17 +122,120 @@ func run(opts *cmdOpts) error {
 	return nil
 }
 
+// newStopPolicy constructs the StopPolicy deciding which tweets are part of the thread from the flags
+func newStopPolicy(opts *cmdOpts) thread.StopPolicy {
+	authorPolicy := thread.SingleAuthor()
+	switch {
+	case opts.anyAuthor:
+		authorPolicy = thread.AnyAuthor()
+	case len(opts.allowedAuthors) > 0:
+		authorPolicy = thread.AllowedAuthors(opts.allowedAuthors...)
+	}
+
+	policies := []thread.StopPolicy{authorPolicy, thread.MaxLength(opts.maxTweets)}
+	if opts.stopAtID != "" {
+		policies = append(policies, thread.StopAtTweet(opts.stopAtID))
+	}
+	return thread.AllOf(policies...)
+}
+
+// newClients constructs the HTTP client and the Client used for saving threads, which are shared by all
+// threads of a batch so that the API's rate limits are tracked across them
+func newClients(opts *cmdOpts) (*http.Client, twitter.Client, error) {
+	httpClient, hErr := newHTTPClient(opts)
+	if hErr != nil {
+		return nil, nil, hErr
+	}
+
+	client, cErr := newClient(opts, httpClient)
+	if cErr != nil {
+		return nil, nil, fmt.Errorf("failed to create Twitter API client: %w", cErr)
+	}
+	return httpClient, client, nil
+}
+
+// newClient constructs a Client that uses the token saved by the login command if one exists and
+// the bearer token otherwise. API requests are made with httpClient, while the saved token is refreshed
+// using a client that neither records nor replays responses.
+func newClient(opts *cmdOpts, httpClient *http.Client) (twitter.Client, error) {
+	clientOpts := opts.clientOpts
+	clientOpts.HTTPClient = httpClient
+	clientOpts.RateLimitNotify = func(reset time.Time) {
+		fmt.Printf("rate limit reached, waiting until %s\n", reset.Format(time.Kitchen))
+	}
+
+	if opts.replay != "" || !opts.oauthStore.Exists() {
+		return twitter.NewClient(opts.token, clientOpts)
+	}
+
+	// Token refreshes bypass any recording, as their responses contain the access and refresh tokens
+	authHTTPClient, hErr := opts.clientOpts.NewHTTPClient()
+	if hErr != nil {
+		return nil, hErr
+	}
+	authorizer, err := auth.NewAuthorizer(opts.authConf, opts.oauthStore, authHTTPClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved login, run login again: %w", err)
+	}
+	return twitter.NewClientWithAuthorizer(authorizer, clientOpts)
+}
+
+// newHTTPClient constructs the HTTP client used for both API requests and attachment downloads,
+// which records or replays responses if specified
+func newHTTPClient(opts *cmdOpts) (*http.Client, error) {
+	transport, err := opts.clientOpts.Transport()
+	if err != nil {
+		return nil, fmt.Errorf("invalid network settings: %w", err)
+	}
+
+	switch {
+	case opts.record != "":
+		transport, err = twitter.NewRecorder(opts.record, transport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up recording to %s: %w", opts.record, err)
+		}
+	case opts.replay != "":
+		transport, err = twitter.NewReplayer(opts.replay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up replay from %s: %w", opts.replay, err)
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.clientOpts.Timeout,
+	}, nil
+}
+
 type cmdOpts struct {
 	// Args
 	name    string
 	tweetID string
 	// Flags
-	css           string
-	template      string
-	noAttachments bool
+	css            string
+	template       string
+	noAttachments  bool
+	videoQuality   twitter.VideoQuality
+	imageSize      twitter.ImageSize
+	replyDepth     thread.ReplyDepth
+	anyAuthor      bool
+	allowAuthors   string
+	allowedAuthors []string
+	stopAt         string
+	stopAtID       string
+	maxTweets      int
+	batch          string
+	record         string
+	replay         string
+	// Build information
+	version string
 	// Environment variables
-	path  string
-	token string
+	path       string
+	token      string
+	clientOpts twitter.Options
+	authConf   auth.Config
+	oauthStore *auth.Store
+	blobStore  *blobstore.Store
 }
 
 func attachOpts(cmd *flag.FlagSet, opts *cmdOpts) {
@@ -90,6 +246,19 @@ func attachOpts(cmd *flag.FlagSet, opts *cmdOpts) {
 	cmd.StringVar(&opts.template, "template", "", "optional path to template file")
 
 	cmd.BoolVar(&opts.noAttachments, "no-attachments", false, "do not download media attachments")
+	cmd.Var(&opts.videoQuality, "video-quality", "video variant to download: best, worst, or a maximum bit rate")
+	cmd.Var(&opts.imageSize, "image-size", "image size to download: default, orig, large, medium, small, or thumb")
+	cmd.Var(&opts.replyDepth, "with-replies", "also save replies from other users, optionally limited to a number of levels")
+
+	cmd.BoolVar(&opts.anyAuthor, "any-author", false, "follow the thread through tweets by any author")
+	cmd.StringVar(&opts.allowAuthors, "allow-authors", "", "comma separated handles of other authors whose tweets continue the thread")
+	cmd.StringVar(&opts.stopAt, "stop-at", "", "URL or ID of the first tweet of the thread")
+	cmd.IntVar(&opts.maxTweets, "max-tweets", thread.DefaultMaxLength, "maximum number of tweets in the thread")
+
+	cmd.StringVar(&opts.batch, "batch", "", "file listing names and URLs of threads to save")
+
+	cmd.StringVar(&opts.record, "record", "", "directory in which to record API and media responses")
+	cmd.StringVar(&opts.replay, "replay", "", "directory from which to replay recorded responses instead of using the network")
 }
 
 func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
@@ -100,23 +269,107 @@ func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
 	if err != nil {
 		return err
 	}
+	if withRepliesDepthArg(args, cmd.NArg()) {
+		return fmt.Errorf("argument %s follows flag 'with-replies' without a value: use --with-replies=%s to limit the depth of replies", cmd.Arg(0), cmd.Arg(0))
+	}
 
-	tweetID, tErr := parseTweetID(cmd.Arg(1))
-	if tErr != nil {
-		return tErr
+	if opts.batch == "" {
+		tweetID, tErr := parseTweetID(cmd.Arg(1))
+		if tErr != nil {
+			return tErr
+		}
+		opts.tweetID = tweetID
+		opts.name = cmd.Arg(0)
+	} else if cmd.NArg() > 0 {
+		return errors.New("arguments 'name' and 'last-tweet' cannot be used with flag 'batch'")
+	}
+
+	if opts.stopAt != "" {
+		stopAtID, sErr := parseTweetID(opts.stopAt)
+		if sErr != nil {
+			return sErr
+		}
+		opts.stopAtID = stopAtID
+	}
+	for _, handle := range strings.Split(opts.allowAuthors, ",") {
+		if handle = strings.TrimSpace(handle); handle != "" {
+			opts.allowedAuthors = append(opts.allowedAuthors, handle)
+		}
 	}
-	opts.tweetID = tweetID
-	opts.name = cmd.Arg(0)
 
-	envArgs := env.Parse()
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
 	opts.path = envArgs.Path
+	if opts.template == "" {
+		opts.template = envArgs.Template
+	}
+	if opts.css == "" {
+		opts.css = envArgs.CSS
+	}
+	if !env.FlagPassed(cmd, "no-attachments") {
+		opts.noAttachments = envArgs.NoAttachments
+	}
+	if !env.FlagPassed(cmd, "video-quality") {
+		videoQuality, vErr := twitter.ParseVideoQuality(envArgs.VideoQuality)
+		if vErr != nil {
+			return vErr
+		}
+		opts.videoQuality = videoQuality
+	}
+	if !env.FlagPassed(cmd, "image-size") {
+		imageSize, iErr := twitter.ParseImageSize(envArgs.ImageSize)
+		if iErr != nil {
+			return iErr
+		}
+		opts.imageSize = imageSize
+	}
 	opts.token = envArgs.Token
 
+	clientOpts, cErr := envArgs.ClientOptions()
+	if cErr != nil {
+		return cErr
+	}
+	opts.clientOpts = clientOpts
+	opts.authConf = envArgs.AuthConfig()
+	opts.oauthStore = envArgs.OAuthStore()
+	blobStore, bErr := envArgs.BlobStore()
+	if bErr != nil {
+		return bErr
+	}
+	opts.blobStore = blobStore
+
+	// Only read the secret store when it is needed, as the encrypted file backend prompts for a passphrase
+	if opts.token == "" && opts.replay == "" && !opts.oauthStore.Exists() {
+		token, sErr := envArgs.StoredToken()
+		if sErr != nil {
+			return sErr
+		}
+		opts.token = token
+	}
+
 	if opts.path == "" {
 		return errs.ErrEmptyPath
 	}
-	if opts.token == "" {
-		return fmt.Errorf("token must be specified in %s or by the environment variable %s", env.TokenFilePath(), env.VarToken)
+	if opts.record != "" && opts.replay != "" {
+		return errors.New("flags 'record' and 'replay' cannot be used together")
+	}
+	// Replayed responses do not require authorization
+	if opts.token == "" && opts.replay == "" && !opts.oauthStore.Exists() {
+		return fmt.Errorf("token must be specified in %s or by the environment variable %s, or saved by the token or login command", env.ConfigFilePath(), env.VarToken)
+	}
+	if opts.anyAuthor && len(opts.allowedAuthors) > 0 {
+		return errors.New("flags 'any-author' and 'allow-authors' cannot be used together")
+	}
+	if opts.maxTweets < 1 {
+		return errors.New("flag 'max-tweets' must be a positive number")
+	}
+	if opts.batch != "" {
+		if opts.stopAt != "" {
+			return errors.New("flags 'batch' and 'stop-at' cannot be used together")
+		}
+		return nil
 	}
 	if strings.TrimSpace(opts.name) == "" {
 		return errors.New("argument 'name' cannot be empty")
@@ -124,10 +377,29 @@ func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
 	if opts.tweetID == "" {
 		return errors.New("argument 'last-tweet' cannot be empty")
 	}
+	if opts.stopAtID != "" && twitter.LessID(opts.tweetID, opts.stopAtID) {
+		return errors.New("flag 'stop-at' must be a tweet no later than argument 'last-tweet'")
+	}
 	return nil
 }
 
 // parseTweetID extracts a tweet ID from its URL or returns the original input if provided the ID
+// withRepliesDepthArg evaluates if the first of nArg positional arguments remaining after parsing args
+// is a number that directly follows a bare with-replies flag, as the flag is boolean and so takes its
+// depth only in the form --with-replies=depth
+func withRepliesDepthArg(args []string, nArg int) bool {
+	nFlagArgs := len(args) - nArg
+	if nArg == 0 || nFlagArgs == 0 {
+		return false
+	}
+	lastFlagArg := args[nFlagArgs-1]
+	if lastFlagArg != "-with-replies" && lastFlagArg != "--with-replies" {
+		return false
+	}
+	_, err := strconv.ParseUint(args[nFlagArgs], 10, 0)
+	return err == nil
+}
+
 func parseTweetID(urlOrID string) (string, error) {
 	u, err := url.Parse(urlOrID)
 	if err != nil {
@@ -145,7 +417,7 @@ func parseTweetID(urlOrID string) (string, error) {
 
 func setUsage(appName string, cmd *flag.FlagSet) {
 	cmd.Usage = func() {
-		fmt.Printf(usage, cmd.Name(), appName, cmd.Name())
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name(), appName, cmd.Name())
 		fmt.Printf("\n\n%s\n", env.Usage())
 	}
 }
@@ -154,12 +426,23 @@ const usage = `'%s' saves thread content and generates a local html file
 
 Usage:
   %s %s [flags] <name> <last-tweet>
+  %s %s [flags] --batch <file>
 
 Args:
   name           string  name to use for the thread
-  last-tweet     string  URL or ID of the last tweet in a single-author thread
+  last-tweet     string  URL or ID of the last tweet in the thread
 
 Flags:
   -c, --css             string  optional path to CSS file
   -t, --template        string  optional path to template file
-      --no-attachments          do not download attachments`
+      --no-attachments          do not download attachments
+      --video-quality   string  video variant to download: best (default), worst, or a maximum bit rate in bits per second
+      --image-size      string  image size to download: default, orig, large, medium, small, or thumb
+      --with-replies[=depth]    also save replies from other users, optionally limited to a number of levels
+      --any-author              follow the thread through tweets by any author
+      --allow-authors   string  comma separated handles of other authors whose tweets continue the thread
+      --stop-at         string  URL or ID of the first tweet of the thread
+      --max-tweets      int     maximum number of tweets in the thread (default 100)
+      --batch           string  file listing the names and URLs of threads to save instead of the args
+      --record          string  directory in which to record API and media responses
+      --replay          string  directory from which to replay recorded responses instead of using the network`
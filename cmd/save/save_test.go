@@ -0,0 +1,163 @@
+package save
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/pkg/config"
+	"github.com/dkaslovsky/thread-safe/pkg/thread"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+const (
+	fixturesNathanMacKinnon = "../../pkg/twitter/twittertest/testdata/Nathan_MacKinnon_2018"
+	fixturesFeatures        = "../../pkg/twitter/twittertest/testdata/features"
+)
+
+func TestSaveThread(t *testing.T) {
+	tests := map[string]struct {
+		fixtures            string
+		tweetID             string
+		noAttachments       bool
+		replyDepth          thread.ReplyDepth
+		expectedTweets      int
+		expectedReplies     bool
+		expectedAttachments bool
+	}{
+		"thread with attachments": {
+			fixtures:            fixturesNathanMacKinnon,
+			tweetID:             "969990907490484225",
+			expectedTweets:      8,
+			expectedAttachments: true,
+		},
+		"thread with replies and without attachments": {
+			fixtures:        fixturesFeatures,
+			tweetID:         "1700000000000000007",
+			noAttachments:   true,
+			replyDepth:      thread.RepliesAll,
+			expectedTweets:  7,
+			expectedReplies: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := twittertest.NewClient(t, test.fixtures)
+
+			opts := &cmdOpts{
+				noAttachments: test.noAttachments,
+				replyDepth:    test.replyDepth,
+				maxTweets:     thread.DefaultMaxLength,
+				path:          t.TempDir(),
+			}
+			th := thread.New(opts.path, "thread")
+
+			sErr := saveThread(th, test.tweetID, opts, client, http.DefaultClient)
+			if sErr != nil {
+				t.Fatalf("unexpected error: %v", sErr)
+			}
+
+			saved, fErr := thread.FromJSON(opts.path, "thread")
+			if fErr != nil {
+				t.Fatalf("failed to read saved thread: %v", fErr)
+			}
+			if saved.Len() != test.expectedTweets {
+				t.Errorf("expected %d tweets, got %d", test.expectedTweets, saved.Len())
+			}
+			if (len(saved.Replies) > 0) != test.expectedReplies {
+				t.Errorf("expected replies %t, got %d", test.expectedReplies, len(saved.Replies))
+			}
+
+			if _, hErr := os.Stat(filepath.Join(opts.path, "thread", "thread.html")); hErr != nil {
+				t.Errorf("expected HTML file: %v", hErr)
+			}
+			attachments, _ := os.ReadDir(filepath.Join(opts.path, "thread", "attachments"))
+			if (len(attachments) > 0) != test.expectedAttachments {
+				t.Errorf("expected attachments %t, got %d files", test.expectedAttachments, len(attachments))
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := map[string]struct {
+		args               []string
+		expectedTweetID    string
+		expectedStopAtID   string
+		expectedReplyDepth thread.ReplyDepth
+		expectedErr        bool
+	}{
+		"last tweet": {
+			args:            []string{"thread", "https://twitter.com/thread_safe_dev/status/1700000000000000007"},
+			expectedTweetID: "1700000000000000007",
+		},
+		"stop at earlier tweet": {
+			args:             []string{"--stop-at", "1700000000000000002", "thread", "1700000000000000007"},
+			expectedTweetID:  "1700000000000000007",
+			expectedStopAtID: "1700000000000000002",
+		},
+		"stop at last tweet": {
+			args:             []string{"--stop-at", "1700000000000000007", "thread", "1700000000000000007"},
+			expectedTweetID:  "1700000000000000007",
+			expectedStopAtID: "1700000000000000007",
+		},
+		"stop at later tweet": {
+			args:        []string{"--stop-at", "1700000000000000009", "thread", "1700000000000000007"},
+			expectedErr: true,
+		},
+		"with replies": {
+			args:               []string{"--with-replies", "thread", "1700000000000000007"},
+			expectedTweetID:    "1700000000000000007",
+			expectedReplyDepth: thread.RepliesAll,
+		},
+		"with replies depth": {
+			args:               []string{"--with-replies=2", "thread", "1700000000000000007"},
+			expectedTweetID:    "1700000000000000007",
+			expectedReplyDepth: 2,
+		},
+		"with replies depth as argument": {
+			args:        []string{"--with-replies", "2", "thread", "1700000000000000007"},
+			expectedErr: true,
+		},
+		"missing last tweet": {
+			args:        []string{"thread"},
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(config.VarConfig, filepath.Join(t.TempDir(), "config"))
+			t.Setenv(env.VarPath, t.TempDir())
+			t.Setenv(env.VarToken, "token")
+
+			cmd := flag.NewFlagSet("save", flag.ContinueOnError)
+			opts := &cmdOpts{}
+			attachOpts(cmd, opts)
+
+			err := parseArgs(cmd, opts, test.args)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if opts.tweetID != test.expectedTweetID {
+				t.Errorf("expected tweet ID %s, got %s", test.expectedTweetID, opts.tweetID)
+			}
+			if opts.stopAtID != test.expectedStopAtID {
+				t.Errorf("expected stop at ID %s, got %s", test.expectedStopAtID, opts.stopAtID)
+			}
+			if opts.replyDepth != test.expectedReplyDepth {
+				t.Errorf("expected reply depth %s, got %s", test.expectedReplyDepth, opts.replyDepth)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package token
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/secret"
+	"golang.org/x/term"
+)
+
+// Run executes the package's (sub)command
+func Run(appName string, args []string) error {
+	cmd := flag.NewFlagSet("token", flag.ExitOnError)
+	opts := &cmdOpts{}
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		if errors.Is(err, errs.ErrNoArgs) {
+			cmd.Usage()
+			return nil
+		}
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	store, err := opts.envArgs.SecretStore()
+	if err != nil {
+		return err
+	}
+	key := opts.envArgs.SecretKey()
+
+	switch opts.action {
+	case "get":
+		token, gErr := store.Get(key)
+		if errors.Is(gErr, secret.ErrNotFound) {
+			return fmt.Errorf("no token saved in %s", store.Name())
+		}
+		if gErr != nil {
+			return fmt.Errorf("failed to read token: %w", gErr)
+		}
+		fmt.Println(token)
+	case "set":
+		token := opts.token
+		if token == "" {
+			t, rErr := readToken()
+			if rErr != nil {
+				return rErr
+			}
+			token = t
+		}
+		if token == "" {
+			return errors.New("token cannot be empty")
+		}
+		sErr := store.Set(key, token)
+		if sErr != nil {
+			return fmt.Errorf("failed to save token: %w", sErr)
+		}
+		fmt.Printf("Token saved in %s\n", store.Name())
+		if opts.envArgs.Token != "" {
+			fmt.Printf("The token set in %s or by %s takes precedence over the saved token and can now be removed\n",
+				env.ConfigFilePath(), env.VarToken)
+		}
+	case "delete":
+		dErr := store.Delete(key)
+		if errors.Is(dErr, secret.ErrNotFound) {
+			return fmt.Errorf("no token saved in %s", store.Name())
+		}
+		if dErr != nil {
+			return fmt.Errorf("failed to delete token: %w", dErr)
+		}
+		fmt.Printf("Token deleted from %s\n", store.Name())
+	}
+
+	return nil
+}
+
+// readToken prompts for a token without echoing it when running in a terminal and reads a line from
+// standard input otherwise
+func readToken() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Print("Token: ")
+		b, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read token: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+type cmdOpts struct {
+	// Args
+	action string
+	token  string
+	// Environment variables
+	envArgs *env.Args
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	if len(args) == 0 {
+		return errs.ErrNoArgs
+	}
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	opts.action = cmd.Arg(0)
+	switch opts.action {
+	case "get", "delete":
+		if cmd.NArg() != 1 {
+			return fmt.Errorf("action '%s' does not take arguments", opts.action)
+		}
+	case "set":
+		if cmd.NArg() > 2 {
+			return errors.New("action 'set' takes at most one argument")
+		}
+		opts.token = cmd.Arg(1)
+	default:
+		return fmt.Errorf("unknown action \"%s\"", opts.action)
+	}
+
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
+	opts.envArgs = envArgs
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name(), env.VarPassphrase)
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' saves the bearer token in the OS secret store or an encrypted file
+
+Usage:
+  %s %s <action> [token]
+
+Actions:
+  set [token]  save the token, reading it from the terminal or standard input if not provided
+  get          print the saved token
+  delete       delete the saved token
+
+The token is kept in the OS secret store (the Secret Service over D-Bus on Linux) when one is
+available and otherwise in a file encrypted with a passphrase, which is read from %s or
+prompted for. Set the secret_store configuration key to auto, keyring, or file to choose.
+Each profile has its own saved token.`
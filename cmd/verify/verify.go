@@ -0,0 +1,207 @@
+package verify
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/dkaslovsky/thread-safe/cmd/env"
+	"github.com/dkaslovsky/thread-safe/cmd/errs"
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+	"github.com/dkaslovsky/thread-safe/pkg/thread"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// Run executes the package's (sub)command
+func Run(appName string, args []string) error {
+	cmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	opts := &cmdOpts{}
+	attachOpts(cmd, opts)
+	setUsage(appName, cmd)
+
+	err := parseArgs(cmd, opts, args)
+	if err != nil {
+		return err
+	}
+
+	return run(opts)
+}
+
+func run(opts *cmdOpts) error {
+	names := opts.names
+	if len(names) == 0 {
+		all, err := thread.ListNames(opts.path)
+		if err != nil {
+			return fmt.Errorf("failed to list threads in %s: %w", opts.path, err)
+		}
+		names = all
+	}
+
+	var httpClient *http.Client
+	if opts.repair {
+		client, err := opts.clientOpts.NewHTTPClient()
+		if err != nil {
+			return fmt.Errorf("invalid network settings: %w", err)
+		}
+		httpClient = client
+	}
+
+	found, repaired := 0, 0
+	for _, name := range names {
+		f, r := verify(name, httpClient, opts)
+		found += f
+		repaired += r
+	}
+
+	fmt.Printf("verified %d thread(s): %d problem(s) found, %d repaired\n", len(names), found, repaired)
+	if found > repaired {
+		return fmt.Errorf("%d problem(s) remain", found-repaired)
+	}
+	return nil
+}
+
+// verify checks a single thread, repairing problems if httpClient is not nil, and returns the number of
+// problems found and repaired
+func verify(name string, httpClient *http.Client, opts *cmdOpts) (int, int) {
+	th, err := thread.FromJSON(opts.path, name)
+	if err != nil {
+		// A thread without valid JSON cannot be repaired
+		fmt.Printf("%s: failed to load thread JSON file: %v\n", name, err)
+		return 1, 0
+	}
+
+	// Check the HTML file before any repair rewrites the JSON file
+	stale := th.HTMLIsStale()
+
+	found, repaired := 0, 0
+	changed := false
+
+	for _, problem := range th.VerifyAttachments() {
+		found++
+		fmt.Printf("%s: %v\n", name, problem.Err)
+		if httpClient == nil {
+			continue
+		}
+		rErr := th.RepairAttachment(httpClient, problem)
+		if rErr != nil {
+			fmt.Printf("%s: failed to repair attachment %s: %v\n", name, problem.Attachment.MediaKey, rErr)
+			continue
+		}
+		fmt.Printf("%s: downloaded attachment %s again\n", name, problem.Attachment.MediaKey)
+		repaired++
+		changed = true
+	}
+
+	if changed {
+		jErr := th.ToJSON()
+		if jErr != nil {
+			fmt.Printf("%s: failed to write thread JSON file: %v\n", name, jErr)
+			return found, 0
+		}
+		if opts.blobStore != nil {
+			sErr := th.StoreAttachments(opts.blobStore)
+			if sErr != nil {
+				fmt.Printf("%s: failed to store thread attachment files in %s: %v\n", name, opts.blobStore.Dir(), sErr)
+			}
+		}
+	}
+
+	if stale {
+		found++
+		fmt.Printf("%s: HTML file is older than the thread JSON file\n", name)
+	}
+	if httpClient != nil && (stale || changed) {
+		hErr := th.ToHTML(opts.template, opts.css)
+		if hErr != nil {
+			fmt.Printf("%s: failed to write thread HTML file: %v\n", name, hErr)
+		} else if stale {
+			fmt.Printf("%s: regenerated HTML file\n", name)
+			repaired++
+		}
+	}
+
+	return found, repaired
+}
+
+type cmdOpts struct {
+	// Args
+	names []string
+	// Flags
+	css      string
+	template string
+	repair   bool
+	// Environment variables
+	path       string
+	clientOpts twitter.Options
+	blobStore  *blobstore.Store
+}
+
+func attachOpts(cmd *flag.FlagSet, opts *cmdOpts) {
+	cmd.StringVar(&opts.css, "c", "", "optional path to CSS file used when regenerating HTML")
+	cmd.StringVar(&opts.css, "css", "", "optional path to CSS file used when regenerating HTML")
+
+	cmd.StringVar(&opts.template, "t", "", "optional path to template file used when regenerating HTML")
+	cmd.StringVar(&opts.template, "template", "", "optional path to template file used when regenerating HTML")
+
+	cmd.BoolVar(&opts.repair, "repair", false, "download damaged attachments again and regenerate out of date HTML")
+}
+
+func parseArgs(cmd *flag.FlagSet, opts *cmdOpts, args []string) error {
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	opts.names = cmd.Args()
+
+	envArgs, eErr := env.Parse()
+	if eErr != nil {
+		return eErr
+	}
+	opts.path = envArgs.Path
+	if opts.template == "" {
+		opts.template = envArgs.Template
+	}
+	if opts.css == "" {
+		opts.css = envArgs.CSS
+	}
+
+	clientOpts, cErr := envArgs.ClientOptions()
+	if cErr != nil {
+		return cErr
+	}
+	opts.clientOpts = clientOpts
+
+	blobStore, bErr := envArgs.BlobStore()
+	if bErr != nil {
+		return bErr
+	}
+	opts.blobStore = blobStore
+
+	if opts.path == "" {
+		return errs.ErrEmptyPath
+	}
+	return nil
+}
+
+func setUsage(appName string, cmd *flag.FlagSet) {
+	cmd.Usage = func() {
+		fmt.Printf(usage, cmd.Name(), appName, cmd.Name())
+		fmt.Printf("\n\n%s\n", env.Usage())
+	}
+}
+
+const usage = `'%s' checks saved threads for missing or damaged files
+
+Usage:
+  %s %s [flags] [name...]
+
+Args:
+  name  string  names given to the threads to check (all threads if unset)
+
+Each thread's JSON file must parse, each attachment must exist and match the size and SHA-256
+digest recorded when it was saved, and the HTML file must be newer than the JSON file.
+
+Flags:
+  -c, --css       string  optional path to CSS file used when regenerating HTML
+  -t, --template  string  optional path to template file used when regenerating HTML
+      --repair            download damaged attachments again and regenerate out of date HTML`
@@ -2,4 +2,18 @@ module github.com/dkaslovsky/thread-safe
 
 go 1.19
 
-require github.com/g8rswimmer/go-twitter/v2 v2.1.4
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/g8rswimmer/go-twitter/v2 v2.1.4
+	github.com/zalando/go-keyring v0.2.3
+	golang.org/x/crypto v0.11.0
+	golang.org/x/oauth2 v0.20.0
+	golang.org/x/term v0.10.0
+)
+
+require (
+	github.com/alessio/shellescape v1.4.1 // indirect
+	github.com/danieljoos/wincred v1.2.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+)
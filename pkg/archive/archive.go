@@ -0,0 +1,371 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+const (
+	// dirNameData is the name of the archive directory containing the data files
+	dirNameData = "data"
+	// dirNameTweetsMedia is the name of the data directory containing tweet media files
+	dirNameTweetsMedia = "tweets_media"
+
+	// fileNameAccount is the name of the data file containing account information
+	fileNameAccount = "account.js"
+	// fileGlobsTweets are patterns for data files containing tweets, covering both current and older archive formats
+	fileGlobsTweets = "tweets.js,tweets-part*.js,tweet.js,tweet-part*.js"
+
+	// timeFormatArchive is the format of timestamps in an archive
+	timeFormatArchive = "Mon Jan 02 15:04:05 -0700 2006"
+	// timeFormatAPI is the format of timestamps returned by the Twitter API
+	timeFormatAPI = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// mediaKeyPrefixes maps media types to the prefix used by the Twitter API when constructing media keys
+var mediaKeyPrefixes = map[string]string{
+	twitter.MediaTypePhoto: "3",
+	twitter.MediaTypeVideo: "7",
+	twitter.MediaTypeGIF:   "16",
+}
+
+// Archive represents the contents of a Twitter account data archive
+type Archive struct {
+	dir     string
+	account account
+	tweets  map[string]*archiveTweet
+}
+
+// Open loads the account and tweets from an extracted Twitter data archive
+func Open(dir string) (*Archive, error) {
+	dataDir := filepath.Join(dir, dirNameData)
+
+	accounts := []struct {
+		Account account `json:"account"`
+	}{}
+	err := readDataFile(filepath.Join(dataDir, fileNameAccount), &accounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account data: %w", err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no account found in %s", filepath.Join(dataDir, fileNameAccount))
+	}
+
+	fileNames := []string{}
+	for _, pattern := range strings.Split(fileGlobsTweets, ",") {
+		matches, err := filepath.Glob(filepath.Join(dataDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		fileNames = append(fileNames, matches...)
+	}
+	if len(fileNames) == 0 {
+		return nil, fmt.Errorf("no tweet data files found in %s", dataDir)
+	}
+
+	tweets := map[string]*archiveTweet{}
+	for _, fileName := range fileNames {
+		entries := []struct {
+			Tweet archiveTweet `json:"tweet"`
+		}{}
+		err := readDataFile(fileName, &entries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tweet data: %w", err)
+		}
+		for i := range entries {
+			tweet := entries[i].Tweet
+			tweets[tweet.ID] = &tweet
+		}
+	}
+
+	return &Archive{
+		dir:     dir,
+		account: accounts[0].Account,
+		tweets:  tweets,
+	}, nil
+}
+
+// Handle returns the handle of the account that generated the archive
+func (a *Archive) Handle() string {
+	return a.account.UserName
+}
+
+// ThreadEnds returns the IDs of the last tweet of every self-reply chain in the archive, sorted in
+// ascending order
+func (a *Archive) ThreadEnds() []string {
+	hasSelfReply := map[string]bool{}
+	for _, tweet := range a.tweets {
+		if parentID, ok := a.selfParent(tweet); ok {
+			hasSelfReply[parentID] = true
+		}
+	}
+
+	ends := []string{}
+	for id, tweet := range a.tweets {
+		if hasSelfReply[id] {
+			continue
+		}
+		if _, ok := a.selfParent(tweet); ok {
+			ends = append(ends, id)
+		}
+	}
+
+	sort.Slice(ends, func(i, j int) bool {
+		return twitter.LessID(ends[i], ends[j])
+	})
+	return ends
+}
+
+// MediaFile returns the path to the file in the archive containing an attachment and a bool
+// indicating if the file exists
+func (a *Archive) MediaFile(tweetID string, attachment twitter.Attachment) (string, bool) {
+	// Media files are named by the tweet ID and the base name of the media URL
+	base := strings.SplitN(filepath.Base(attachment.URL), "?", 2)[0]
+	fileName := filepath.Join(a.dir, dirNameData, dirNameTweetsMedia, fmt.Sprintf("%s-%s", tweetID, base))
+	_, err := os.Stat(fileName)
+	return fileName, err == nil
+}
+
+// selfParent returns the ID of the archived tweet to which a tweet replies and a bool indicating
+// if the tweet is a reply to another tweet in the archive
+func (a *Archive) selfParent(tweet *archiveTweet) (string, bool) {
+	if tweet.InReplyToStatusID == "" || tweet.InReplyToUserID != a.account.ID {
+		return "", false
+	}
+	_, ok := a.tweets[tweet.InReplyToStatusID]
+	return tweet.InReplyToStatusID, ok
+}
+
+// conversationID follows a tweet's chain of self-replies to the earliest archived tweet and returns
+// the ID of that tweet or, if that tweet is itself a reply, the ID of the tweet to which it replies.
+// Archives do not record conversation IDs, so for a chain starting with a reply to a tweet that is itself
+// a reply, the ID returned is not the ID of the conversation's first tweet. It is the same for every tweet
+// of the chain, which is all that walking the chain requires.
+func (a *Archive) conversationID(tweet *archiveTweet) string {
+	cur := tweet
+	for i := 0; i < len(a.tweets); i++ {
+		parentID, ok := a.selfParent(cur)
+		if !ok {
+			break
+		}
+		cur = a.tweets[parentID]
+	}
+	if cur.InReplyToStatusID != "" {
+		return cur.InReplyToStatusID
+	}
+	return cur.ID
+}
+
+// toTweet constructs a Tweet from an archived tweet
+func (a *Archive) toTweet(tweet *archiveTweet) *twitter.Tweet {
+	repliedToIDs := []string{}
+	if tweet.InReplyToStatusID != "" {
+		repliedToIDs = append(repliedToIDs, tweet.InReplyToStatusID)
+	}
+
+	attachments := []twitter.Attachment{}
+	for _, media := range tweet.ExtendedEntities.Media {
+		attachment := twitter.Attachment{
+			MediaKey:   fmt.Sprintf("%s_%s", mediaKeyPrefixes[media.Type], media.ID),
+			Type:       media.Type,
+			URL:        media.URL,
+			AltText:    media.AltText,
+			Width:      jsonInt(media.Sizes.Large.Width),
+			Height:     jsonInt(media.Sizes.Large.Height),
+			DurationMS: jsonInt(media.VideoInfo.DurationMillis),
+		}
+		if media.Type != twitter.MediaTypePhoto {
+			attachment.PreviewImageURL = media.URL
+		}
+
+		// Keep all video variants and default to the one with the largest bit rate, as is done when
+		// querying the API
+		for _, variant := range media.VideoInfo.Variants {
+			attachment.Variants = append(attachment.Variants, twitter.Variant{
+				URL:         variant.URL,
+				BitRate:     variant.bitRate(),
+				ContentType: variant.ContentType,
+			})
+		}
+		attachment = attachment.WithVideoQuality(twitter.VideoQuality{})
+
+		attachments = append(attachments, attachment)
+	}
+
+	createdAt := tweet.CreatedAt
+	if t, err := time.Parse(timeFormatArchive, tweet.CreatedAt); err == nil {
+		createdAt = t.UTC().Format(timeFormatAPI)
+	}
+
+	return &twitter.Tweet{
+		ID:             tweet.ID,
+		ConversationID: a.conversationID(tweet),
+		URL:            fmt.Sprintf("https://twitter.com/%s/status/%s", a.account.UserName, tweet.ID),
+		Text:           tweet.FullText,
+		CreatedAt:      createdAt,
+		AuthorID:       a.account.ID,
+		AuthorName:     a.account.DisplayName,
+		AuthorHandle:   a.account.UserName,
+		RepliedToIDs:   repliedToIDs,
+		Attachments:    attachments,
+		Entities:       tweet.Entities.toEntities(),
+		Author:         a.author(),
+	}
+}
+
+// author constructs the profile of the account that generated the archive, which does not include the
+// account's public metrics
+func (a *Archive) author() *twitter.User {
+	createdAt := a.account.CreatedAt
+	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+		createdAt = t.UTC().Format(timeFormatAPI)
+	}
+	return &twitter.User{
+		ID:        a.account.ID,
+		Name:      a.account.DisplayName,
+		Handle:    a.account.UserName,
+		CreatedAt: createdAt,
+	}
+}
+
+type account struct {
+	ID          string `json:"accountId"`
+	UserName    string `json:"username"`
+	DisplayName string `json:"accountDisplayName"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type archiveTweet struct {
+	ID                string          `json:"id_str"`
+	FullText          string          `json:"full_text"`
+	CreatedAt         string          `json:"created_at"`
+	InReplyToStatusID string          `json:"in_reply_to_status_id_str"`
+	InReplyToUserID   string          `json:"in_reply_to_user_id_str"`
+	Entities          archiveEntities `json:"entities"`
+	ExtendedEntities  struct {
+		Media []archiveMedia `json:"media"`
+	} `json:"extended_entities"`
+}
+
+// archiveEntities holds entities in the format of the v1.1 API, which locates entities by a pair of
+// [start, end] indices that archives store as strings
+type archiveEntities struct {
+	URLs []struct {
+		URL         string         `json:"url"`
+		ExpandedURL string         `json:"expanded_url"`
+		DisplayURL  string         `json:"display_url"`
+		Indices     archiveIndices `json:"indices"`
+	} `json:"urls"`
+	UserMentions []struct {
+		ScreenName string         `json:"screen_name"`
+		Indices    archiveIndices `json:"indices"`
+	} `json:"user_mentions"`
+	Hashtags []archiveTag `json:"hashtags"`
+	Symbols  []archiveTag `json:"symbols"`
+}
+
+type archiveTag struct {
+	Text    string         `json:"text"`
+	Indices archiveIndices `json:"indices"`
+}
+
+type archiveIndices []json.Number
+
+func (i archiveIndices) bounds() (int, int) {
+	if len(i) != 2 {
+		return -1, -1
+	}
+	start, sErr := i[0].Int64()
+	end, eErr := i[1].Int64()
+	if sErr != nil || eErr != nil {
+		return -1, -1
+	}
+	return int(start), int(end)
+}
+
+// toEntities converts archived entities, whose positions are resolved against the text when rendering
+func (e archiveEntities) toEntities() twitter.Entities {
+	entities := twitter.Entities{}
+	for _, u := range e.URLs {
+		start, end := u.Indices.bounds()
+		entities.URLs = append(entities.URLs, twitter.URLEntity{
+			Start:       start,
+			End:         end,
+			URL:         u.URL,
+			ExpandedURL: u.ExpandedURL,
+			DisplayURL:  u.DisplayURL,
+		})
+	}
+	for _, m := range e.UserMentions {
+		start, end := m.Indices.bounds()
+		entities.Mentions = append(entities.Mentions, twitter.MentionEntity{Start: start, End: end, Handle: m.ScreenName})
+	}
+	for _, h := range e.Hashtags {
+		start, end := h.Indices.bounds()
+		entities.Hashtags = append(entities.Hashtags, twitter.TagEntity{Start: start, End: end, Tag: h.Text})
+	}
+	for _, c := range e.Symbols {
+		start, end := c.Indices.bounds()
+		entities.Cashtags = append(entities.Cashtags, twitter.TagEntity{Start: start, End: end, Tag: c.Text})
+	}
+	return entities
+}
+
+type archiveMedia struct {
+	ID      string `json:"id_str"`
+	Type    string `json:"type"`
+	URL     string `json:"media_url_https"`
+	AltText string `json:"ext_alt_text"`
+	Sizes   struct {
+		Large struct {
+			Width  json.Number `json:"w"`
+			Height json.Number `json:"h"`
+		} `json:"large"`
+	} `json:"sizes"`
+	VideoInfo struct {
+		DurationMillis json.Number      `json:"duration_millis"`
+		Variants       []archiveVariant `json:"variants"`
+	} `json:"video_info"`
+}
+
+type archiveVariant struct {
+	BitRate     json.Number `json:"bitrate"`
+	ContentType string      `json:"content_type"`
+	URL         string      `json:"url"`
+}
+
+func (v archiveVariant) bitRate() int {
+	return jsonInt(v.BitRate)
+}
+
+// jsonInt converts a number that archives store as either a JSON number or string, returning 0 if
+// it is missing or invalid
+func jsonInt(n json.Number) int {
+	i, err := n.Int64()
+	if err != nil {
+		return 0
+	}
+	return int(i)
+}
+
+// readDataFile parses an archive data file, which is a JSON array assigned to a JavaScript variable
+func readDataFile(fileName string, v any) error {
+	b, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return err
+	}
+
+	parts := strings.SplitN(string(b), "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("unexpected format of archive data file %s", fileName)
+	}
+
+	return json.Unmarshal([]byte(parts[1]), v)
+}
@@ -0,0 +1,337 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// fixtureArchive is an extracted archive of the account archived_dev, whose tweets form a chain
+// 1001 <- 1002 <- 1003 <- 1005 with 1004 forking from 1002, a chain 2002 continuing 2001, a reply to
+// another account, a retweet, and a reply to a deleted tweet
+const fixtureArchive = "testdata/archive"
+
+func openFixture(t *testing.T) *Archive {
+	t.Helper()
+	a, err := Open(fixtureArchive)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	return a
+}
+
+func TestOpen(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]string
+		expectedErr string
+	}{
+		"missing account": {
+			files:       map[string]string{"tweets.js": "window.YTD.tweets.part0 = []"},
+			expectedErr: "failed to read account data",
+		},
+		"empty account": {
+			files:       map[string]string{"account.js": "window.YTD.account.part0 = []"},
+			expectedErr: "no account found",
+		},
+		"missing tweets": {
+			files:       map[string]string{"account.js": `window.YTD.account.part0 = [{"account": {"accountId": "100"}}]`},
+			expectedErr: "no tweet data files found",
+		},
+		"invalid data file": {
+			files: map[string]string{
+				"account.js": `window.YTD.account.part0 = [{"account": {"accountId": "100"}}]`,
+				"tweets.js":  "[]",
+			},
+			expectedErr: "unexpected format of archive data file",
+		},
+		"older format in parts": {
+			files: map[string]string{
+				"account.js":      `window.YTD.account.part0 = [{"account": {"accountId": "100", "username": "old"}}]`,
+				"tweet-part1.js":  `window.YTD.tweet.part1 = [{"tweet": {"id_str": "1"}}]`,
+				"tweets-part2.js": `window.YTD.tweets.part2 = [{"tweet": {"id_str": "2"}}]`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			dataDir := filepath.Join(dir, dirNameData)
+			if err := os.MkdirAll(dataDir, 0o750); err != nil {
+				t.Fatalf("failed to create %s: %v", dataDir, err)
+			}
+			for fileName, contents := range test.files {
+				if err := os.WriteFile(filepath.Join(dataDir, fileName), []byte(contents), 0o600); err != nil {
+					t.Fatalf("failed to write %s: %v", fileName, err)
+				}
+			}
+
+			a, err := Open(dir)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(a.tweets) != 2 {
+				t.Errorf("expected 2 tweets, got %d", len(a.tweets))
+			}
+		})
+	}
+}
+
+func TestThreadEnds(t *testing.T) {
+	a := openFixture(t)
+	if a.Handle() != "archived_dev" {
+		t.Errorf("expected handle archived_dev, got %s", a.Handle())
+	}
+
+	// Both ends of the fork are returned, while the retweet, the standalone tweet, the reply to another
+	// account, and the reply to a deleted tweet are not the end of a chain
+	expected := []string{"1004", "1005", "2002"}
+	if ends := a.ThreadEnds(); !reflect.DeepEqual(ends, expected) {
+		t.Errorf("expected thread ends %v, got %v", expected, ends)
+	}
+}
+
+func TestSelfParent(t *testing.T) {
+	tests := map[string]struct {
+		id               string
+		expectedParentID string
+		expectedOK       bool
+	}{
+		"reply to own archived tweet": {
+			id:               "1003",
+			expectedParentID: "1002",
+			expectedOK:       true,
+		},
+		"not a reply": {
+			id:               "1001",
+			expectedParentID: "",
+			expectedOK:       false,
+		},
+		"retweet": {
+			id:               "3001",
+			expectedParentID: "",
+			expectedOK:       false,
+		},
+		"reply to another account": {
+			id:               "2001",
+			expectedParentID: "",
+			expectedOK:       false,
+		},
+		"reply to deleted tweet": {
+			id:               "4001",
+			expectedParentID: "4000",
+			expectedOK:       false,
+		},
+	}
+
+	a := openFixture(t)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			parentID, ok := a.selfParent(a.tweets[test.id])
+			if parentID != test.expectedParentID || ok != test.expectedOK {
+				t.Errorf("expected (%q, %t), got (%q, %t)", test.expectedParentID, test.expectedOK, parentID, ok)
+			}
+		})
+	}
+}
+
+func TestConversationID(t *testing.T) {
+	tests := map[string]struct {
+		id       string
+		expected string
+	}{
+		"first tweet":               {id: "1001", expected: "1001"},
+		"end of chain":              {id: "1005", expected: "1001"},
+		"end of fork":               {id: "1004", expected: "1001"},
+		"chain replying to another": {id: "2002", expected: "9001"},
+		"reply to another account":  {id: "2001", expected: "9001"},
+		"reply to deleted tweet":    {id: "4001", expected: "4000"},
+	}
+
+	a := openFixture(t)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := a.conversationID(a.tweets[test.id]); got != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestToTweet(t *testing.T) {
+	tests := map[string]struct {
+		id                  string
+		expectedRepliedTo   []string
+		expectedCreatedAt   string
+		expectedAttachments []twitter.Attachment
+		expectedHTML        string
+	}{
+		"first tweet with hashtag": {
+			id:                  "1001",
+			expectedRepliedTo:   []string{},
+			expectedCreatedAt:   "2023-09-09T15:00:00.000Z",
+			expectedAttachments: []twitter.Attachment{},
+			expectedHTML:        `A thread about archives <a href="https://twitter.com/hashtag/ThreadSafe">#ThreadSafe</a>`,
+		},
+		"photo": {
+			id:                "1003",
+			expectedRepliedTo: []string{"1002"},
+			expectedCreatedAt: "2023-09-09T15:10:00.000Z",
+			expectedAttachments: []twitter.Attachment{{
+				MediaKey: "3_5001",
+				Type:     twitter.MediaTypePhoto,
+				URL:      "https://pbs.twimg.com/media/photo1.jpg",
+				AltText:  "A photo",
+				Width:    1200,
+				Height:   675,
+			}},
+			expectedHTML: "Media is copied from the archive https://t.co/Ph0to",
+		},
+		"video defaults to largest bit rate": {
+			id:                "1004",
+			expectedRepliedTo: []string{"1002"},
+			expectedCreatedAt: "2023-09-09T15:12:00.000Z",
+			expectedAttachments: []twitter.Attachment{{
+				MediaKey:        "7_5002",
+				Type:            twitter.MediaTypeVideo,
+				URL:             "https://video.twimg.com/ext_tw_video/5002/pu/vid/1280x720/video1.mp4?tag=12",
+				Width:           1280,
+				Height:          720,
+				DurationMS:      12000,
+				PreviewImageURL: "https://pbs.twimg.com/ext_tw_video_thumb/5002/pu/img/video1.jpg",
+				Variants: []twitter.Variant{
+					{URL: "https://video.twimg.com/ext_tw_video/5002/pu/vid/640x360/video1.mp4", BitRate: 832000, ContentType: "video/mp4"},
+					{URL: "https://video.twimg.com/ext_tw_video/5002/pu/pl/video1.m3u8", ContentType: "application/x-mpegURL"},
+					{URL: "https://video.twimg.com/ext_tw_video/5002/pu/vid/1280x720/video1.mp4?tag=12", BitRate: 2176000, ContentType: "video/mp4"},
+				},
+			}},
+			expectedHTML: "A second reply to the same tweet forks the chain",
+		},
+		"reply with mention": {
+			id:                  "2001",
+			expectedRepliedTo:   []string{"9001"},
+			expectedCreatedAt:   "2023-09-10T10:00:00.000Z",
+			expectedAttachments: []twitter.Attachment{},
+			expectedHTML:        `<a href="https://twitter.com/someone">@someone</a> Replying to another account`,
+		},
+	}
+
+	a := openFixture(t)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tweet := a.toTweet(a.tweets[test.id])
+			if tweet.ID != test.id {
+				t.Errorf("expected ID %s, got %s", test.id, tweet.ID)
+			}
+			if tweet.AuthorID != "100" || tweet.AuthorHandle != "archived_dev" || tweet.AuthorName != "Archived Dev" {
+				t.Errorf("expected author archived_dev, got %s %s %s", tweet.AuthorID, tweet.AuthorHandle, tweet.AuthorName)
+			}
+			if expectedURL := "https://twitter.com/archived_dev/status/" + test.id; tweet.URL != expectedURL {
+				t.Errorf("expected URL %s, got %s", expectedURL, tweet.URL)
+			}
+			if !reflect.DeepEqual(tweet.RepliedToIDs, test.expectedRepliedTo) {
+				t.Errorf("expected replied to IDs %v, got %v", test.expectedRepliedTo, tweet.RepliedToIDs)
+			}
+			if tweet.CreatedAt != test.expectedCreatedAt {
+				t.Errorf("expected created at %s, got %s", test.expectedCreatedAt, tweet.CreatedAt)
+			}
+			if !reflect.DeepEqual(tweet.Attachments, test.expectedAttachments) {
+				t.Errorf("expected attachments %+v, got %+v", test.expectedAttachments, tweet.Attachments)
+			}
+			if html := tweet.HTML(); html != test.expectedHTML {
+				t.Errorf("expected HTML %q, got %q", test.expectedHTML, html)
+			}
+			if tweet.Author == nil || tweet.Author.CreatedAt != "2020-01-02T03:04:05.000Z" {
+				t.Errorf("expected author profile, got %+v", tweet.Author)
+			}
+		})
+	}
+}
+
+func TestMediaFile(t *testing.T) {
+	tests := map[string]struct {
+		tweetID          string
+		url              string
+		expectedFileName string
+		expectedOK       bool
+	}{
+		"photo": {
+			tweetID:          "1003",
+			url:              "https://pbs.twimg.com/media/photo1.jpg",
+			expectedFileName: "1003-photo1.jpg",
+			expectedOK:       true,
+		},
+		"URL with query": {
+			tweetID:          "1003",
+			url:              "https://pbs.twimg.com/media/photo1.jpg?name=orig",
+			expectedFileName: "1003-photo1.jpg",
+			expectedOK:       true,
+		},
+		"missing media": {
+			tweetID:          "1004",
+			url:              "https://video.twimg.com/ext_tw_video/5002/pu/vid/1280x720/video1.mp4?tag=12",
+			expectedFileName: "1004-video1.mp4",
+			expectedOK:       false,
+		},
+		"media of another tweet": {
+			tweetID:          "1005",
+			url:              "https://pbs.twimg.com/media/photo1.jpg",
+			expectedFileName: "1005-photo1.jpg",
+			expectedOK:       false,
+		},
+	}
+
+	a := openFixture(t)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fileName, ok := a.MediaFile(test.tweetID, twitter.Attachment{URL: test.url})
+			expectedFileName := filepath.Join(fixtureArchive, dirNameData, dirNameTweetsMedia, test.expectedFileName)
+			if fileName != expectedFileName {
+				t.Errorf("expected file %s, got %s", expectedFileName, fileName)
+			}
+			if ok != test.expectedOK {
+				t.Errorf("expected exists %t, got %t", test.expectedOK, ok)
+			}
+		})
+	}
+}
+
+func TestClientLookupTweet(t *testing.T) {
+	tests := map[string]struct {
+		id             string
+		expectedAuthor string
+	}{
+		"archived tweet": {
+			id:             "1002",
+			expectedAuthor: "100",
+		},
+		// Tweets missing from the archive have no author, which ends a thread
+		"tweet of another account": {
+			id:             "9001",
+			expectedAuthor: "",
+		},
+	}
+
+	client := openFixture(t).Client()
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tweet, err := client.LookupTweet(test.id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tweet.ID != test.id || tweet.AuthorID != test.expectedAuthor {
+				t.Errorf("expected tweet %s by %q, got %s by %q", test.id, test.expectedAuthor, tweet.ID, tweet.AuthorID)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package archive
+
+import (
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// Client returns a twitter.Client that looks up tweets from an Archive rather than the Twitter API
+func (a *Archive) Client() twitter.Client {
+	return &archiveClient{a: a}
+}
+
+type archiveClient struct {
+	a *Archive
+}
+
+func (ac *archiveClient) LookupTweet(tweetID string) (*twitter.Tweet, error) {
+	tweet, ok := ac.a.tweets[tweetID]
+	if !ok {
+		// Tweets missing from the archive were authored by another account, so return a tweet without
+		// an author to signal the end of a thread
+		return &twitter.Tweet{ID: tweetID}, nil
+	}
+	return ac.a.toTweet(tweet), nil
+}
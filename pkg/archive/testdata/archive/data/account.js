@@ -0,0 +1,12 @@
+window.YTD.account.part0 = [
+  {
+    "account" : {
+      "email" : "archived@example.com",
+      "createdVia" : "web",
+      "username" : "archived_dev",
+      "accountId" : "100",
+      "createdAt" : "2020-01-02T03:04:05.000Z",
+      "accountDisplayName" : "Archived Dev"
+    }
+  }
+]
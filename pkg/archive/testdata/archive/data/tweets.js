@@ -0,0 +1,136 @@
+window.YTD.tweets.part0 = [
+  {
+    "tweet" : {
+      "id_str" : "1001",
+      "full_text" : "A thread about archives #ThreadSafe",
+      "created_at" : "Sat Sep 09 15:00:00 +0000 2023",
+      "entities" : {
+        "hashtags" : [ { "text" : "ThreadSafe", "indices" : [ "24", "35" ] } ],
+        "symbols" : [ ],
+        "user_mentions" : [ ],
+        "urls" : [ ]
+      }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1002",
+      "full_text" : "Replies to yourself form a chain",
+      "created_at" : "Sat Sep 09 15:05:00 +0000 2023",
+      "in_reply_to_status_id_str" : "1001",
+      "in_reply_to_user_id_str" : "100",
+      "entities" : { }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1003",
+      "full_text" : "Media is copied from the archive https://t.co/Ph0to",
+      "created_at" : "Sat Sep 09 15:10:00 +0000 2023",
+      "in_reply_to_status_id_str" : "1002",
+      "in_reply_to_user_id_str" : "100",
+      "entities" : {
+        "urls" : [ ]
+      },
+      "extended_entities" : {
+        "media" : [
+          {
+            "id_str" : "5001",
+            "type" : "photo",
+            "media_url_https" : "https://pbs.twimg.com/media/photo1.jpg",
+            "ext_alt_text" : "A photo",
+            "sizes" : { "large" : { "w" : "1200", "h" : "675" } }
+          }
+        ]
+      }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1004",
+      "full_text" : "A second reply to the same tweet forks the chain",
+      "created_at" : "Sat Sep 09 15:12:00 +0000 2023",
+      "in_reply_to_status_id_str" : "1002",
+      "in_reply_to_user_id_str" : "100",
+      "entities" : { },
+      "extended_entities" : {
+        "media" : [
+          {
+            "id_str" : "5002",
+            "type" : "video",
+            "media_url_https" : "https://pbs.twimg.com/ext_tw_video_thumb/5002/pu/img/video1.jpg",
+            "sizes" : { "large" : { "w" : "1280", "h" : "720" } },
+            "video_info" : {
+              "duration_millis" : "12000",
+              "variants" : [
+                { "bitrate" : "832000", "content_type" : "video/mp4", "url" : "https://video.twimg.com/ext_tw_video/5002/pu/vid/640x360/video1.mp4" },
+                { "content_type" : "application/x-mpegURL", "url" : "https://video.twimg.com/ext_tw_video/5002/pu/pl/video1.m3u8" },
+                { "bitrate" : "2176000", "content_type" : "video/mp4", "url" : "https://video.twimg.com/ext_tw_video/5002/pu/vid/1280x720/video1.mp4?tag=12" }
+              ]
+            }
+          }
+        ]
+      }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1005",
+      "full_text" : "The end of the first chain",
+      "created_at" : "Sat Sep 09 15:15:00 +0000 2023",
+      "in_reply_to_status_id_str" : "1003",
+      "in_reply_to_user_id_str" : "100",
+      "entities" : { }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1006",
+      "full_text" : "A tweet without replies is not a thread",
+      "created_at" : "Sat Sep 09 16:00:00 +0000 2023",
+      "entities" : { }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "2001",
+      "full_text" : "@someone Replying to another account",
+      "created_at" : "Sun Sep 10 10:00:00 +0000 2023",
+      "in_reply_to_status_id_str" : "9001",
+      "in_reply_to_user_id_str" : "200",
+      "entities" : {
+        "user_mentions" : [ { "screen_name" : "someone", "indices" : [ "0", "8" ] } ]
+      }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "2002",
+      "full_text" : "And continuing the reply as a thread",
+      "created_at" : "Sun Sep 10 10:05:00 +0000 2023",
+      "in_reply_to_status_id_str" : "2001",
+      "in_reply_to_user_id_str" : "100",
+      "entities" : { }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "3001",
+      "full_text" : "RT @someone: Retweets are archived as tweets",
+      "created_at" : "Mon Sep 11 09:00:00 +0000 2023",
+      "entities" : {
+        "user_mentions" : [ { "screen_name" : "someone", "indices" : [ "3", "11" ] } ]
+      }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "4001",
+      "full_text" : "A reply to a deleted tweet",
+      "created_at" : "Tue Sep 12 09:00:00 +0000 2023",
+      "in_reply_to_status_id_str" : "4000",
+      "in_reply_to_user_id_str" : "100",
+      "entities" : { }
+    }
+  }
+]
@@ -0,0 +1,230 @@
+// Package auth implements OAuth 2.0 user-context authentication with the Twitter API
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// authURL is the URL of the page on which a user authorizes the application
+	authURL = "https://twitter.com/i/oauth2/authorize"
+	// tokenPath is the path of the API endpoint for exchanging and refreshing tokens
+	tokenPath = "/2/oauth2/token" // nolint:gosec
+
+	// DefaultRedirectPort is the default port of the loopback redirect URI
+	DefaultRedirectPort = 8976
+	// callbackPath is the path of the loopback redirect URI
+	callbackPath = "/callback"
+	// loginTimeout is the time allowed for the user to complete authorization
+	loginTimeout = 5 * time.Minute
+)
+
+// scopes are the permissions requested for reading threads, including those of protected accounts,
+// with offline.access allowing tokens to be refreshed without user interaction
+var scopes = []string{"tweet.read", "users.read", "offline.access"}
+
+// Config holds the settings of an application registered with the Twitter API
+type Config struct {
+	ClientID     string // OAuth 2.0 client ID of the application
+	ClientSecret string // OAuth 2.0 client secret, required only for confidential clients
+	Host         string // Base URL of the Twitter API
+	RedirectPort int    // Port of the loopback redirect URI http://127.0.0.1:<port>/callback
+}
+
+// RedirectURL returns the loopback redirect URI that must be registered for the application
+func (c Config) RedirectURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d%s", c.RedirectPort, callbackPath)
+}
+
+func (c Config) oauth2Config() *oauth2.Config {
+	authStyle := oauth2.AuthStyleInParams
+	if c.ClientSecret != "" {
+		authStyle = oauth2.AuthStyleInHeader
+	}
+	return &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   authURL,
+			TokenURL:  strings.TrimSuffix(c.Host, "/") + tokenPath,
+			AuthStyle: authStyle,
+		},
+		RedirectURL: c.RedirectURL(),
+		Scopes:      scopes,
+	}
+}
+
+// Login runs the OAuth 2.0 Authorization Code flow with PKCE, calling prompt with the URL the user must
+// visit and waiting for the authorization code to be delivered to a loopback redirect listener
+func Login(conf Config, httpClient *http.Client, prompt func(authURL string)) (*oauth2.Token, error) {
+	oauthConf := conf.oauth2Config()
+
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	listener, lErr := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", conf.RedirectPort))
+	if lErr != nil {
+		return nil, fmt.Errorf("failed to start redirect listener: %w", lErr)
+	}
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		res := result{code: query.Get("code")}
+		switch {
+		case query.Get("state") != state:
+			res.err = errors.New("authorization response has an invalid state")
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s", query.Get("error"))
+		case res.code == "":
+			res.err = errors.New("authorization response is missing a code")
+		}
+
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			_, _ = fmt.Fprintln(w, "Authorization complete, you may close this window.")
+		}
+
+		select {
+		case results <- res:
+		default:
+		}
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	prompt(authCodeURL(oauthConf, state, verifier))
+
+	var res result
+	select {
+	case res = <-results:
+	case <-time.After(loginTimeout):
+		return nil, errors.New("timed out waiting for authorization")
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	return oauthConf.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+}
+
+// authCodeURL returns the URL of the page on which the user authorizes the application, carrying the
+// S256 code challenge derived from verifier
+func authCodeURL(oauthConf *oauth2.Config, state string, verifier string) string {
+	return oauthConf.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// Authorizer adds user-context authorization to API requests, refreshing and saving the stored token
+// as needed
+type Authorizer struct {
+	ts oauth2.TokenSource
+}
+
+// NewAuthorizer constructs an Authorizer from a token previously saved to store
+func NewAuthorizer(conf Config, store *Store, httpClient *http.Client) (*Authorizer, error) {
+	tok, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	a := &Authorizer{
+		ts: &savingTokenSource{
+			src:   conf.oauth2Config().TokenSource(ctx, tok),
+			store: store,
+			last:  tok.AccessToken,
+		},
+	}
+
+	// Obtain a valid token up front so that a login that can no longer be refreshed is reported before
+	// any request is made
+	_, tErr := a.token()
+	if tErr != nil {
+		return nil, tErr
+	}
+	return a, nil
+}
+
+// Authorize sets the Authorization header of a request, refreshing the token if it has expired
+func (a *Authorizer) Authorize(req *http.Request) error {
+	tok, err := a.token()
+	if err != nil {
+		return fmt.Errorf("failed to authorize request, run login again: %w", err)
+	}
+	tok.SetAuthHeader(req)
+	return nil
+}
+
+// Add sets the Authorization header of a request, leaving it unset if a valid token cannot be obtained,
+// for use where errors cannot be returned. Authorize is used for API requests.
+func (a *Authorizer) Add(req *http.Request) {
+	_ = a.Authorize(req)
+}
+
+// token returns a valid token, refreshing it if it has expired
+func (a *Authorizer) token() (*oauth2.Token, error) {
+	tok, err := a.ts.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh saved login: %w", err)
+	}
+	return tok, nil
+}
+
+// savingTokenSource saves tokens to a Store whenever they are refreshed
+type savingTokenSource struct {
+	src   oauth2.TokenSource
+	store *Store
+	last  string
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok.AccessToken != s.last {
+		s.last = tok.AccessToken
+		// A failure to save only means the token is refreshed again on the next run
+		_ = s.store.Save(tok)
+	}
+	return tok, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestAuthCodeURL(t *testing.T) {
+	// Test vector from RFC 7636 Appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	expectedChallenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	conf := Config{ClientID: "id", RedirectPort: DefaultRedirectPort}
+	u, err := url.Parse(authCodeURL(conf.oauth2Config(), "state", verifier))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if base := u.Scheme + "://" + u.Host + u.Path; base != authURL {
+		t.Errorf("expected %s, got %s", authURL, base)
+	}
+	expected := map[string]string{
+		"code_challenge":        expectedChallenge,
+		"code_challenge_method": "S256",
+		"state":                 "state",
+		"client_id":             "id",
+		"redirect_uri":          "http://127.0.0.1:8976/callback",
+		"response_type":         "code",
+		"scope":                 "tweet.read users.read offline.access",
+	}
+	query := u.Query()
+	for key, value := range expected {
+		if got := query.Get(key); got != value {
+			t.Errorf("expected %s %q, got %q", key, value, got)
+		}
+	}
+	if query.Has("code_verifier") {
+		t.Error("expected code verifier not to be sent to the authorization page")
+	}
+}
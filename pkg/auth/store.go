@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/oauth2"
+)
+
+// keySize is the size in bytes of the AES-256 key used to encrypt stored tokens
+const keySize = 32
+
+// Store saves OAuth 2.0 tokens to a file encrypted with AES-GCM using a key kept in a separate file
+type Store struct {
+	tokenFile string
+	keyFile   string
+}
+
+// NewStore constructs a Store using the provided token and key file paths
+func NewStore(tokenFile string, keyFile string) *Store {
+	return &Store{
+		tokenFile: filepath.Clean(tokenFile),
+		keyFile:   filepath.Clean(keyFile),
+	}
+}
+
+// Exists evaluates if a Store contains a saved token
+func (s *Store) Exists() bool {
+	_, err := os.Stat(s.tokenFile)
+	return err == nil
+}
+
+// Load reads and decrypts a saved token
+func (s *Store) Load() (*oauth2.Token, error) {
+	key, err := os.ReadFile(s.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token encryption key: %w", err)
+	}
+	ciphertext, rErr := os.ReadFile(s.tokenFile)
+	if rErr != nil {
+		return nil, fmt.Errorf("failed to read saved token: %w", rErr)
+	}
+
+	gcm, gErr := newGCM(key)
+	if gErr != nil {
+		return nil, gErr
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("saved token is corrupt")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, oErr := gcm.Open(nil, nonce, sealed, nil)
+	if oErr != nil {
+		return nil, fmt.Errorf("failed to decrypt saved token: %w", oErr)
+	}
+
+	tok := &oauth2.Token{}
+	jErr := json.Unmarshal(plaintext, tok)
+	if jErr != nil {
+		return nil, fmt.Errorf("failed to parse saved token: %w", jErr)
+	}
+	return tok, nil
+}
+
+// Save encrypts and writes a token, generating an encryption key if one does not exist
+func (s *Store) Save(tok *oauth2.Token) error {
+	key, err := s.loadOrCreateKey()
+	if err != nil {
+		return err
+	}
+	plaintext, jErr := json.Marshal(tok)
+	if jErr != nil {
+		return jErr
+	}
+
+	gcm, gErr := newGCM(key)
+	if gErr != nil {
+		return gErr
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, rErr := io.ReadFull(rand.Reader, nonce)
+	if rErr != nil {
+		return rErr
+	}
+
+	return os.WriteFile(s.tokenFile, gcm.Seal(nonce, nonce, plaintext, nil), 0o600)
+}
+
+// Delete removes a saved token
+func (s *Store) Delete() error {
+	err := os.Remove(s.tokenFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) loadOrCreateKey() ([]byte, error) {
+	key, err := os.ReadFile(s.keyFile)
+	if err == nil {
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read token encryption key: %w", err)
+	}
+
+	key = make([]byte, keySize)
+	_, rErr := io.ReadFull(rand.Reader, key)
+	if rErr != nil {
+		return nil, rErr
+	}
+	dErr := os.MkdirAll(filepath.Dir(s.keyFile), 0o700)
+	if dErr != nil {
+		return nil, dErr
+	}
+	wErr := os.WriteFile(s.keyFile, key, 0o600)
+	if wErr != nil {
+		return nil, fmt.Errorf("failed to write token encryption key: %w", wErr)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, errors.New("token encryption key is invalid")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
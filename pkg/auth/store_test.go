@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	dir := t.TempDir()
+	return NewStore(filepath.Join(dir, "token"), filepath.Join(dir, "config", "oauth.key"))
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	if store.Exists() {
+		t.Fatal("expected no saved token")
+	}
+
+	tok := &oauth2.Token{
+		AccessToken:  "access",
+		TokenType:    "bearer",
+		RefreshToken: "refresh",
+		Expiry:       time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := store.Save(tok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !store.Exists() {
+		t.Fatal("expected saved token")
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.AccessToken != tok.AccessToken || loaded.TokenType != tok.TokenType ||
+		loaded.RefreshToken != tok.RefreshToken || !loaded.Expiry.Equal(tok.Expiry) {
+		t.Errorf("expected %+v, got %+v", tok, loaded)
+	}
+
+	// The token is not saved in plaintext and neither file is readable by others
+	b, rErr := os.ReadFile(store.tokenFile)
+	if rErr != nil {
+		t.Fatalf("unexpected error: %v", rErr)
+	}
+	if strings.Contains(string(b), "refresh") {
+		t.Error("expected saved token to be encrypted")
+	}
+	for _, fileName := range []string{store.tokenFile, store.keyFile} {
+		info, sErr := os.Stat(fileName)
+		if sErr != nil {
+			t.Fatalf("unexpected error: %v", sErr)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("expected permissions 0600 for %s, got %#o", fileName, perm)
+		}
+	}
+
+	if dErr := store.Delete(); dErr != nil {
+		t.Fatalf("unexpected error: %v", dErr)
+	}
+	if store.Exists() {
+		t.Error("expected token deleted")
+	}
+	if dErr := store.Delete(); dErr != nil {
+		t.Errorf("expected deleting a missing token to succeed, got %v", dErr)
+	}
+}
+
+func TestStoreLoadErrors(t *testing.T) {
+	tests := map[string]struct {
+		// damage modifies the files of a Store after a token is saved
+		damage func(t *testing.T, store *Store)
+	}{
+		"tampered ciphertext": {
+			damage: func(t *testing.T, store *Store) {
+				b := readTestFile(t, store.tokenFile)
+				b[len(b)-1] ^= 0xff
+				writeTestFile(t, store.tokenFile, b)
+			},
+		},
+		"truncated ciphertext": {
+			damage: func(t *testing.T, store *Store) {
+				writeTestFile(t, store.tokenFile, readTestFile(t, store.tokenFile)[:4])
+			},
+		},
+		"different key": {
+			damage: func(t *testing.T, store *Store) {
+				other := newTestStore(t)
+				if err := other.Save(&oauth2.Token{AccessToken: "other"}); err != nil {
+					t.Fatalf("failed to save token: %v", err)
+				}
+				writeTestFile(t, store.keyFile, readTestFile(t, other.keyFile))
+			},
+		},
+		"invalid key": {
+			damage: func(t *testing.T, store *Store) {
+				writeTestFile(t, store.keyFile, []byte("short"))
+			},
+		},
+		"missing key": {
+			damage: func(t *testing.T, store *Store) {
+				if err := os.Remove(store.keyFile); err != nil {
+					t.Fatalf("failed to remove key: %v", err)
+				}
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t)
+			if err := store.Save(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}); err != nil {
+				t.Fatalf("failed to save token: %v", err)
+			}
+			test.damage(t, store)
+
+			tok, err := store.Load()
+			if err == nil {
+				t.Fatalf("expected error, got %+v", tok)
+			}
+			// An unreadable login is reported before any request is made
+			if _, aErr := NewAuthorizer(Config{ClientID: "id"}, store, http.DefaultClient); aErr == nil {
+				t.Error("expected error constructing authorizer")
+			}
+		})
+	}
+}
+
+func readTestFile(t *testing.T, fileName string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", fileName, err)
+	}
+	return b
+}
+
+func writeTestFile(t *testing.T, fileName string, b []byte) {
+	t.Helper()
+	if err := os.WriteFile(fileName, b, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", fileName, err)
+	}
+}
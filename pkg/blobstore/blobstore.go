@@ -0,0 +1,397 @@
+// Package blobstore deduplicates attachment files across threads by storing each distinct file once,
+// keyed by its SHA-256 digest, and linking to it from each thread's attachments directory
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ModeOff disables the blob store so that each thread keeps its own attachment files
+	ModeOff Mode = "off"
+	// ModeHardlink links attachment files to blobs using hard links
+	ModeHardlink Mode = "hardlink"
+	// ModeSymlink links attachment files to blobs using relative symbolic links
+	ModeSymlink Mode = "symlink"
+
+	// dirNameBlobs is the name of the blob store's directory within the top level path for thread files
+	dirNameBlobs = ".blobs"
+	// dirNameSHA256 is the directory containing blobs named by their SHA-256 digest
+	dirNameSHA256 = "sha256"
+	// fileNameIndex is the name of the file recording the files that reference each blob
+	fileNameIndex = "index.json"
+)
+
+// Mode is the way in which attachment files are linked to blobs
+type Mode string
+
+// ParseMode parses a Mode, using ModeOff for an empty string
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeHardlink, ModeSymlink:
+		return Mode(s), nil
+	}
+	return ModeOff, fmt.Errorf("invalid blob store mode %s: expected %s, %s, or %s", s, ModeOff, ModeHardlink, ModeSymlink)
+}
+
+// Store is a content-addressed store of files shared by all threads saved under a top level path
+type Store struct {
+	root  string
+	mode  Mode
+	index index
+}
+
+// index records the files referencing each blob, keyed by the blob's digest
+type index struct {
+	Blobs map[string]*entry `json:"blobs"`
+}
+
+// entry records a blob's size and the paths, relative to the top level path, of the files linked to it
+type entry struct {
+	Size int64    `json:"size"`
+	Refs []string `json:"refs"`
+}
+
+// Open loads the Store located under the top level path root, which links files using mode
+func Open(root string, mode Mode) (*Store, error) {
+	s := &Store{
+		root:  filepath.Clean(root),
+		mode:  mode,
+		index: index{Blobs: map[string]*entry{}},
+	}
+
+	b, err := os.ReadFile(s.indexPath())
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	jErr := json.Unmarshal(b, &s.index)
+	if jErr != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.indexPath(), jErr)
+	}
+	if s.index.Blobs == nil {
+		s.index.Blobs = map[string]*entry{}
+	}
+	for digest := range s.index.Blobs {
+		if !isDigest(digest) {
+			return nil, fmt.Errorf("failed to parse %s: invalid blob digest %q", s.indexPath(), digest)
+		}
+	}
+	return s, nil
+}
+
+// Dir returns the directory containing the Store
+func (s *Store) Dir() string {
+	return filepath.Join(s.root, dirNameBlobs)
+}
+
+// Add moves a file into the Store, or removes it if the Store already contains a file with the same
+// contents, and replaces it with a link to the stored blob
+func (s *Store) Add(fileName string) error {
+	ref, err := filepath.Rel(s.root, filepath.Clean(fileName))
+	if err != nil || strings.HasPrefix(ref, "..") {
+		return fmt.Errorf("%s is not within %s", fileName, s.root)
+	}
+	ref = filepath.ToSlash(ref)
+
+	digest, size, hErr := hashFile(fileName)
+	if hErr != nil {
+		return hErr
+	}
+	blob := s.blobPath(digest)
+
+	blobDigest, _, bErr := hashFile(blob)
+	if bErr != nil && !os.IsNotExist(bErr) {
+		return fmt.Errorf("failed to read blob %s: %w", digest, bErr)
+	}
+	if blobDigest != digest {
+		// Files still linked to a damaged blob are relinked once it is replaced
+		damaged, _ := os.Stat(blob)
+
+		dErr := os.MkdirAll(filepath.Dir(blob), 0o750)
+		if dErr != nil {
+			return dErr
+		}
+		// Copy rather than move so that an existing link to a blob is never moved into the Store. A blob
+		// with the wrong contents, such as one damaged through a hard link, is replaced.
+		cErr := copyFile(fileName, blob)
+		if cErr != nil {
+			return fmt.Errorf("failed to store %s: %w", fileName, cErr)
+		}
+		if damaged != nil {
+			rErr := s.relink(digest, damaged)
+			if rErr != nil {
+				return rErr
+			}
+		}
+	}
+
+	lErr := s.link(blob, fileName)
+	if lErr != nil {
+		return fmt.Errorf("failed to link %s to %s: %w", fileName, blob, lErr)
+	}
+
+	e, ok := s.index.Blobs[digest]
+	if !ok {
+		e = &entry{Size: size}
+		s.index.Blobs[digest] = e
+	}
+	for _, r := range e.Refs {
+		if r == ref {
+			return nil
+		}
+	}
+	e.Refs = append(e.Refs, ref)
+	sort.Strings(e.Refs)
+	return nil
+}
+
+// relink links the files referencing a blob that were hard links to the damaged blob it replaced to the
+// replacement, as they share the damaged contents
+func (s *Store) relink(digest string, damaged os.FileInfo) error {
+	e, ok := s.index.Blobs[digest]
+	if !ok {
+		return nil
+	}
+	blob := s.blobPath(digest)
+	for _, ref := range e.Refs {
+		fileName := filepath.Join(s.root, filepath.FromSlash(ref))
+		info, err := os.Lstat(fileName)
+		if err != nil || !os.SameFile(info, damaged) {
+			continue
+		}
+		lErr := s.link(blob, fileName)
+		if lErr != nil {
+			return fmt.Errorf("failed to link %s to %s: %w", fileName, blob, lErr)
+		}
+	}
+	return nil
+}
+
+// link replaces fileName with a link to blob, which is created beside fileName and then renamed so that
+// fileName is never missing
+func (s *Store) link(blob string, fileName string) error {
+	tmpFileName := fileName + ".link"
+	_ = os.Remove(tmpFileName)
+
+	switch s.mode {
+	case ModeHardlink:
+		err := os.Link(blob, tmpFileName)
+		if err != nil {
+			return err
+		}
+	case ModeSymlink:
+		target, err := filepath.Rel(filepath.Dir(fileName), blob)
+		if err != nil {
+			return err
+		}
+		sErr := os.Symlink(target, tmpFileName)
+		if sErr != nil {
+			return sErr
+		}
+	default:
+		return fmt.Errorf("blob store mode %s does not link files", s.mode)
+	}
+
+	return os.Rename(tmpFileName, fileName)
+}
+
+// Forget drops references from files within dir, such as those of a thread that failed to save and was
+// removed, and writes the Store's index. Blobs left unreferenced are removed by GC.
+func (s *Store) Forget(dir string) error {
+	prefix, err := filepath.Rel(s.root, filepath.Clean(dir))
+	if err != nil || prefix == "." || strings.HasPrefix(prefix, "..") {
+		return fmt.Errorf("%s is not within %s", dir, s.root)
+	}
+	prefix = filepath.ToSlash(prefix) + "/"
+
+	for _, e := range s.index.Blobs {
+		refs := []string{}
+		for _, ref := range e.Refs {
+			if !strings.HasPrefix(ref, prefix) {
+				refs = append(refs, ref)
+			}
+		}
+		e.Refs = refs
+	}
+	return s.Save()
+}
+
+// Save writes the Store's index
+func (s *Store) Save() error {
+	b, err := json.MarshalIndent(s.index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dErr := os.MkdirAll(s.Dir(), 0o750)
+	if dErr != nil {
+		return dErr
+	}
+	tmpFileName := s.indexPath() + ".tmp"
+	wErr := os.WriteFile(tmpFileName, b, 0o600)
+	if wErr != nil {
+		return wErr
+	}
+	return os.Rename(tmpFileName, s.indexPath())
+}
+
+// GCResult summarizes the blobs removed by garbage collection
+type GCResult struct {
+	Removed int   // Number of blobs removed
+	Freed   int64 // Number of bytes freed
+	Kept    int   // Number of blobs still referenced
+}
+
+// GC drops references from files that no longer link to their blob, such as those of deleted threads,
+// and removes blobs that are no longer referenced, only reporting what would be removed if dryRun is true
+func (s *Store) GC(dryRun bool) (GCResult, error) {
+	result := GCResult{}
+
+	for digest, e := range s.index.Blobs {
+		blob := s.blobPath(digest)
+		refs := []string{}
+		for _, ref := range e.Refs {
+			if isLinked(filepath.Join(s.root, filepath.FromSlash(ref)), blob) {
+				refs = append(refs, ref)
+			}
+		}
+		e.Refs = refs
+		if len(refs) > 0 {
+			result.Kept++
+			continue
+		}
+
+		result.Removed++
+		result.Freed += e.Size
+		if dryRun {
+			continue
+		}
+		err := os.Remove(blob)
+		if err != nil && !os.IsNotExist(err) {
+			return result, fmt.Errorf("failed to remove blob %s: %w", digest, err)
+		}
+		delete(s.index.Blobs, digest)
+	}
+
+	// Remove blobs left without an index entry, such as by an interrupted save
+	blobDir := filepath.Join(s.Dir(), dirNameSHA256)
+	wErr := filepath.Walk(blobDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if _, indexed := s.index.Blobs[info.Name()]; indexed {
+			return nil
+		}
+		result.Removed++
+		result.Freed += info.Size()
+		if dryRun {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if wErr != nil {
+		return result, fmt.Errorf("failed to remove unindexed blobs: %w", wErr)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	return result, s.Save()
+}
+
+func (s *Store) blobPath(digest string) string {
+	return filepath.Join(s.Dir(), dirNameSHA256, digest[:2], digest)
+}
+
+func (s *Store) indexPath() string {
+	return filepath.Join(s.Dir(), fileNameIndex)
+}
+
+// isLinked evaluates if fileName is a hard link or symbolic link to blob
+func isLinked(fileName string, blob string) bool {
+	fileInfo, err := os.Stat(fileName)
+	if err != nil {
+		return false
+	}
+	blobInfo, bErr := os.Stat(blob)
+	if bErr != nil {
+		return false
+	}
+	return os.SameFile(fileInfo, blobInfo)
+}
+
+// isDigest evaluates if s is a hex-encoded SHA-256 digest as returned by hashFile
+func isDigest(s string) bool {
+	if len(s) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// hashFile returns the hex-encoded SHA-256 digest and the size of a file
+func hashFile(fileName string) (string, int64, error) {
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	n, cErr := io.Copy(h, f)
+	if cErr != nil {
+		return "", 0, cErr
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	tmpFileName := dst + ".tmp"
+	out, oErr := os.Create(filepath.Clean(tmpFileName))
+	if oErr != nil {
+		return oErr
+	}
+
+	_, cErr := io.Copy(out, in)
+	if clErr := out.Close(); cErr == nil {
+		cErr = clErr
+	}
+	if cErr != nil {
+		_ = os.Remove(tmpFileName)
+		return cErr
+	}
+	return os.Rename(tmpFileName, dst)
+}
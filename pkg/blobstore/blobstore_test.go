@@ -0,0 +1,387 @@
+package blobstore
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]struct {
+		s            string
+		expectedMode Mode
+		expectedErr  bool
+	}{
+		"empty":    {s: "", expectedMode: ModeOff},
+		"off":      {s: "off", expectedMode: ModeOff},
+		"hardlink": {s: "hardlink", expectedMode: ModeHardlink},
+		"symlink":  {s: "symlink", expectedMode: ModeSymlink},
+		"invalid":  {s: "copy", expectedErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mode, err := ParseMode(test.s)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mode != test.expectedMode {
+				t.Errorf("expected mode %s, got %s", test.expectedMode, mode)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := map[string]struct {
+		mode Mode
+	}{
+		"hardlink": {mode: ModeHardlink},
+		"symlink":  {mode: ModeSymlink},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			files := []string{
+				writeFile(t, root, "first/attachments/a.jpg", "shared"),
+				writeFile(t, root, "second/attachments/a.jpg", "shared"),
+				writeFile(t, root, "second/attachments/b.jpg", "distinct"),
+			}
+
+			store, err := Open(root, test.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, fileName := range files {
+				if aErr := store.Add(fileName); aErr != nil {
+					t.Fatalf("unexpected error: %v", aErr)
+				}
+			}
+			// Adding a file that is already linked to its blob leaves it unchanged
+			if aErr := store.Add(files[0]); aErr != nil {
+				t.Fatalf("unexpected error: %v", aErr)
+			}
+			if sErr := store.Save(); sErr != nil {
+				t.Fatalf("unexpected error: %v", sErr)
+			}
+
+			if n := len(blobNames(t, store)); n != 2 {
+				t.Errorf("expected 2 blobs, got %d", n)
+			}
+			for _, fileName := range files {
+				info, lErr := os.Lstat(fileName)
+				if lErr != nil {
+					t.Fatalf("unexpected error: %v", lErr)
+				}
+				if isSymlink := info.Mode()&os.ModeSymlink != 0; isSymlink != (test.mode == ModeSymlink) {
+					t.Errorf("expected %s to be a symbolic link %t", fileName, test.mode == ModeSymlink)
+				}
+			}
+			if got := readFile(t, files[1]); got != "shared" {
+				t.Errorf("expected %s to contain %q, got %q", files[1], "shared", got)
+			}
+
+			reopened, oErr := Open(root, test.mode)
+			if oErr != nil {
+				t.Fatalf("unexpected error: %v", oErr)
+			}
+			expectedRefs := map[int][]string{
+				2: {"first/attachments/a.jpg", "second/attachments/a.jpg"},
+				1: {"second/attachments/b.jpg"},
+			}
+			for _, e := range reopened.index.Blobs {
+				if expected := expectedRefs[len(e.Refs)]; !equalRefs(e.Refs, expected) {
+					t.Errorf("expected refs %v, got %v", expected, e.Refs)
+				}
+			}
+		})
+	}
+}
+
+func TestAddOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	fileName := writeFile(t, t.TempDir(), "a.jpg", "contents")
+
+	store, err := Open(root, ModeHardlink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if aErr := store.Add(fileName); aErr == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGC(t *testing.T) {
+	tests := map[string]struct {
+		dryRun         bool
+		expectedResult GCResult
+		expectedBlobs  int
+	}{
+		"remove": {
+			dryRun:         false,
+			expectedResult: GCResult{Removed: 2, Freed: int64(len("deleted") + len("unindexed")), Kept: 1},
+			expectedBlobs:  1,
+		},
+		"dry run": {
+			dryRun:         true,
+			expectedResult: GCResult{Removed: 2, Freed: int64(len("deleted") + len("unindexed")), Kept: 1},
+			expectedBlobs:  3,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			kept := writeFile(t, root, "kept/attachments/a.jpg", "kept")
+			deleted := writeFile(t, root, "deleted/attachments/a.jpg", "deleted")
+
+			store, err := Open(root, ModeHardlink)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, fileName := range []string{kept, deleted} {
+				if aErr := store.Add(fileName); aErr != nil {
+					t.Fatalf("unexpected error: %v", aErr)
+				}
+			}
+			if sErr := store.Save(); sErr != nil {
+				t.Fatalf("unexpected error: %v", sErr)
+			}
+
+			// Delete a thread and leave a blob without an index entry, as an interrupted save would
+			if rErr := os.RemoveAll(filepath.Join(root, "deleted")); rErr != nil {
+				t.Fatalf("unexpected error: %v", rErr)
+			}
+			writeFile(t, root, ".blobs/sha256/00/00unindexed", "unindexed")
+
+			result, gErr := store.GC(test.dryRun)
+			if gErr != nil {
+				t.Fatalf("unexpected error: %v", gErr)
+			}
+			if result != test.expectedResult {
+				t.Errorf("expected result %+v, got %+v", test.expectedResult, result)
+			}
+			if n := len(blobNames(t, store)); n != test.expectedBlobs {
+				t.Errorf("expected %d blobs, got %d", test.expectedBlobs, n)
+			}
+			if got := readFile(t, kept); got != "kept" {
+				t.Errorf("expected %s to contain %q, got %q", kept, "kept", got)
+			}
+		})
+	}
+}
+
+func TestAddReplacesDamagedBlob(t *testing.T) {
+	tests := map[string]struct {
+		mode Mode
+	}{
+		"hardlink": {mode: ModeHardlink},
+		"symlink":  {mode: ModeSymlink},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			first := writeFile(t, root, "first/attachments/a.jpg", "contents")
+			second := writeFile(t, root, "second/attachments/a.jpg", "contents")
+
+			store, err := Open(root, test.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, fileName := range []string{first, second} {
+				if aErr := store.Add(fileName); aErr != nil {
+					t.Fatalf("unexpected error: %v", aErr)
+				}
+			}
+
+			// Damage the blob through the first file, then replace the first file as a repair would
+			damage(t, first)
+			writeFile(t, root, "first/attachments/a.jpg", "contents")
+
+			if aErr := store.Add(first); aErr != nil {
+				t.Fatalf("unexpected error: %v", aErr)
+			}
+			for _, fileName := range []string{first, second} {
+				if got := readFile(t, fileName); got != "contents" {
+					t.Errorf("expected %s to contain %q, got %q", fileName, "contents", got)
+				}
+			}
+		})
+	}
+}
+
+// damage overwrites the contents of a file in place, which changes the contents of a blob it is linked to
+func damage(t *testing.T, fileName string) {
+	t.Helper()
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", fileName, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, wErr := f.WriteString("damaged"); wErr != nil {
+		t.Fatalf("failed to write %s: %v", fileName, wErr)
+	}
+}
+
+func writeFile(t *testing.T, root string, ref string, contents string) string {
+	t.Helper()
+	fileName := filepath.Join(root, filepath.FromSlash(ref))
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o750); err != nil {
+		t.Fatalf("failed to create directory for %s: %v", fileName, err)
+	}
+	// Replace rather than overwrite so that a link to a blob is not written through
+	_ = os.Remove(fileName)
+	if err := os.WriteFile(fileName, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", fileName, err)
+	}
+	return fileName
+}
+
+// blobNames returns the names of the blob files in a Store
+func blobNames(t *testing.T, store *Store) []string {
+	t.Helper()
+	names := []string{}
+	err := filepath.Walk(filepath.Join(store.Dir(), dirNameSHA256), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			names = append(names, info.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to list blobs: %v", err)
+	}
+	return names
+}
+
+func equalRefs(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func readFile(t *testing.T, fileName string) string {
+	t.Helper()
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", fileName, err)
+	}
+	return string(b)
+}
+
+func TestOpenInvalidIndex(t *testing.T) {
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := map[string]struct {
+		index       string
+		expectedErr bool
+	}{
+		"valid digest": {
+			index:       `{"blobs": {"` + digest + `": {"size": 4, "refs": ["a/attachments/a.jpg"]}}}`,
+			expectedErr: false,
+		},
+		"no blobs": {
+			index:       `{}`,
+			expectedErr: false,
+		},
+		"short digest": {
+			index:       `{"blobs": {"9": {"size": 4, "refs": ["a/attachments/a.jpg"]}}}`,
+			expectedErr: true,
+		},
+		"empty digest": {
+			index:       `{"blobs": {"": {"size": 4, "refs": ["a/attachments/a.jpg"]}}}`,
+			expectedErr: true,
+		},
+		"uppercase digest": {
+			index:       `{"blobs": {"` + strings.ToUpper(digest) + `": {"size": 4, "refs": ["a/attachments/a.jpg"]}}}`,
+			expectedErr: true,
+		},
+		"path in digest": {
+			index:       `{"blobs": {"../../../../../../../../../../../../../../../../../../../../etc/": {"size": 4}}}`,
+			expectedErr: true,
+		},
+		"invalid JSON": {
+			index:       `{"blobs": [`,
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, root, ".blobs/index.json", test.index)
+
+			_, err := Open(root, ModeHardlink)
+			if test.expectedErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !test.expectedErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestForget(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		writeFile(t, root, "failed/attachments/a.jpg", "shared"),
+		writeFile(t, root, "failed/attachments/b.jpg", "distinct"),
+		writeFile(t, root, "failed thread/attachments/a.jpg", "shared"),
+		writeFile(t, root, "saved/attachments/a.jpg", "shared"),
+	}
+
+	store, err := Open(root, ModeHardlink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, fileName := range files {
+		if aErr := store.Add(fileName); aErr != nil {
+			t.Fatalf("unexpected error: %v", aErr)
+		}
+	}
+	if fErr := store.Forget(filepath.Join(root, "failed")); fErr != nil {
+		t.Fatalf("unexpected error: %v", fErr)
+	}
+
+	// The index is saved without the references from within the directory
+	reopened, err := Open(root, ModeHardlink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refs := []string{}
+	for _, e := range reopened.index.Blobs {
+		refs = append(refs, e.Refs...)
+	}
+	sort.Strings(refs)
+	expectedRefs := []string{"failed thread/attachments/a.jpg", "saved/attachments/a.jpg"}
+	if !equalRefs(refs, expectedRefs) {
+		t.Errorf("expected refs %v, got %v", expectedRefs, refs)
+	}
+
+	for _, dir := range []string{root, filepath.Dir(root)} {
+		if fErr := store.Forget(dir); fErr == nil {
+			t.Errorf("expected error forgetting %s", dir)
+		}
+	}
+}
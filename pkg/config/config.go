@@ -0,0 +1,448 @@
+// Package config reads and writes the thread-safe configuration file
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// VarConfig is the name of the environment variable overriding the path of the configuration file
+	VarConfig = "THREAD_SAFE_CONFIG"
+
+	// fileNameHome is the name of the configuration file in the user's $HOME directory
+	fileNameHome = ".thread-safe"
+	// dirNameXDG is the name of the application's directory in $XDG_CONFIG_HOME
+	dirNameXDG = "thread-safe"
+	// fileNameXDG is the name of the configuration file in the application's $XDG_CONFIG_HOME directory
+	fileNameXDG = "config"
+
+	// keyPrefixProfiles is the prefix of the dotted keys of values in named profiles
+	keyPrefixProfiles = "profiles."
+)
+
+// Config represents the contents of the configuration file
+type Config struct {
+	Path        string             `toml:"path,omitempty"`         // Top level path for thread files
+	Token       string             `toml:"token,omitempty"`        // Twitter API bearer token
+	Template    string             `toml:"template,omitempty"`     // Default template file
+	CSS         string             `toml:"css,omitempty"`          // Default CSS file
+	Profile     string             `toml:"profile,omitempty"`      // Name of the profile used by default
+	SecretStore string             `toml:"secret_store,omitempty"` // Backend for the token command: auto, keyring, or file
+	Download    Download           `toml:"download,omitempty"`     // Attachment download settings
+	Network     Network            `toml:"network,omitempty"`      // Network settings
+	OAuth       OAuth              `toml:"oauth,omitempty"`        // OAuth 2.0 application settings
+	Profiles    map[string]Profile `toml:"profiles,omitempty"`     // Named profiles
+}
+
+// Profile holds named values that take precedence over the corresponding top level values
+type Profile struct {
+	Path     string `toml:"path,omitempty"`
+	Token    string `toml:"token,omitempty"`
+	Template string `toml:"template,omitempty"`
+	CSS      string `toml:"css,omitempty"`
+}
+
+// WithProfile returns a copy of a Config with the values of the named profile, or of the default
+// profile if name is empty, taking precedence over top level values
+func (c *Config) WithProfile(name string) (*Config, error) {
+	if name == "" {
+		name = c.Profile
+	}
+	conf := *c
+	if name == "" {
+		return &conf, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s not found in %s", name, FilePath())
+	}
+	if profile.Path != "" {
+		conf.Path = profile.Path
+	}
+	if profile.Token != "" {
+		conf.Token = profile.Token
+	}
+	if profile.Template != "" {
+		conf.Template = profile.Template
+	}
+	if profile.CSS != "" {
+		conf.CSS = profile.CSS
+	}
+	conf.Profile = name
+	return &conf, nil
+}
+
+// ProfileNames returns the sorted names of all profiles
+func (c *Config) ProfileNames() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Download holds settings for downloading attachments
+type Download struct {
+	NoAttachments bool   `toml:"no_attachments,omitempty"` // Do not download attachments by default
+	VideoQuality  string `toml:"video_quality,omitempty"`  // Video variant to download: best, worst, or a maximum bit rate
+	ImageSize     string `toml:"image_size,omitempty"`     // Image size to download: default, orig, large, medium, small, or thumb
+	BlobStore     string `toml:"blob_store,omitempty"`     // Deduplicate attachments across threads: off, hardlink, or symlink
+}
+
+// Network holds settings for network requests
+type Network struct {
+	Host               string `toml:"host,omitempty"`
+	Timeout            string `toml:"timeout,omitempty"`
+	Proxy              string `toml:"proxy,omitempty"`
+	CAFile             string `toml:"ca_file,omitempty"`
+	UserAgent          string `toml:"user_agent,omitempty"`
+	TLSMinVersion      string `toml:"tls_min_version,omitempty"`
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify,omitempty"`
+}
+
+// OAuth holds the settings of an OAuth 2.0 application
+type OAuth struct {
+	ClientID     string `toml:"client_id,omitempty"`
+	ClientSecret string `toml:"client_secret,omitempty"`
+}
+
+// legacyKeys maps keys of the legacy "key = value" file format to configuration keys
+var legacyKeys = map[string]string{
+	"path":                 "path",
+	"token":                "token",
+	"client_id":            "oauth.client_id",
+	"client_secret":        "oauth.client_secret",
+	"host":                 "network.host",
+	"timeout":              "network.timeout",
+	"proxy":                "network.proxy",
+	"ca_file":              "network.ca_file",
+	"user_agent":           "network.user_agent",
+	"tls_min_version":      "network.tls_min_version",
+	"insecure_skip_verify": "network.insecure_skip_verify",
+}
+
+// FilePath returns the path of the configuration file, which is $THREAD_SAFE_CONFIG if set, otherwise
+// $XDG_CONFIG_HOME/thread-safe/config if it exists, otherwise ${HOME}/.thread-safe
+func FilePath() string {
+	if p, ok := os.LookupEnv(VarConfig); ok && p != "" {
+		return filepath.Clean(p)
+	}
+	if xdg, ok := os.LookupEnv("XDG_CONFIG_HOME"); ok && xdg != "" {
+		p := filepath.Join(xdg, dirNameXDG, fileNameXDG)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return filepath.Join(os.ExpandEnv("${HOME}"), fileNameHome)
+}
+
+// Load reads the configuration file, returning an empty Config if it does not exist
+func Load() (*Config, error) {
+	return LoadFile(FilePath())
+}
+
+// LoadFile reads a configuration file, returning an empty Config if it does not exist
+func LoadFile(fileName string) (*Config, error) {
+	conf := &Config{}
+
+	b, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return conf, nil
+		}
+		return nil, err
+	}
+
+	md, tErr := toml.Decode(string(b), conf)
+	if tErr != nil {
+		// Fall back to the legacy format of unquoted "key = value" lines
+		legacy, lErr := parseLegacy(fileName, b)
+		if lErr != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", fileName, tErr)
+		}
+		return legacy, nil
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := []string{}
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return nil, fmt.Errorf("unknown keys in %s: %s", fileName, strings.Join(keys, ", "))
+	}
+
+	return conf, nil
+}
+
+// HasToken evaluates if a Config contains a bearer token at the top level or in any profile
+func (c *Config) HasToken() bool {
+	if c.Token != "" {
+		return true
+	}
+	for _, profile := range c.Profiles {
+		if profile.Token != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadableByOthers evaluates if a file can be read by its group or by all users, which is not
+// determined on Windows where permission bits do not apply
+func ReadableByOthers(fileName string) bool {
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := os.Stat(filepath.Clean(fileName))
+	if err != nil {
+		return false
+	}
+	return info.Mode().Perm()&0o044 != 0
+}
+
+// Save writes a Config to the configuration file
+func (c *Config) Save() error {
+	return c.SaveFile(FilePath())
+}
+
+// SaveFile writes a Config to a file
+func (c *Config) SaveFile(fileName string) error {
+	buf := &bytes.Buffer{}
+	err := toml.NewEncoder(buf).Encode(c)
+	if err != nil {
+		return err
+	}
+
+	dErr := os.MkdirAll(filepath.Dir(fileName), 0o700)
+	if dErr != nil {
+		return dErr
+	}
+	return os.WriteFile(fileName, buf.Bytes(), 0o600)
+}
+
+// Keys returns all configuration keys in dotted form, with keys of profile values using <name> as a
+// placeholder for the profile name
+func Keys() []string {
+	keys := []string{}
+	walkFields(reflect.ValueOf(&Config{}).Elem(), "", func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	walkFields(reflect.ValueOf(&Profile{}).Elem(), keyPrefixProfiles+"<name>.", func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// Keys returns the keys of a Config's values in dotted form, which are the top level keys followed by the
+// keys of each of its profiles
+func (c *Config) Keys() []string {
+	keys := []string{}
+	walkFields(reflect.ValueOf(&Config{}).Elem(), "", func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+	for _, name := range c.ProfileNames() {
+		walkFields(reflect.ValueOf(&Profile{}).Elem(), keyPrefixProfiles+name+".", func(key string, _ reflect.Value) {
+			keys = append(keys, key)
+		})
+	}
+	return keys
+}
+
+// Get returns the value of a configuration key as a string
+func (c *Config) Get(key string) (string, error) {
+	if name, profileKey, ok := splitProfileKey(key); ok {
+		profile, exists := c.Profiles[name]
+		if !exists {
+			return "", fmt.Errorf("profile %s not found", name)
+		}
+		field, err := fieldByKey(reflect.ValueOf(&profile).Elem(), profileKey, key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(field.Interface()), nil
+	}
+
+	field, err := fieldByKey(reflect.ValueOf(c).Elem(), key, key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(field.Interface()), nil
+}
+
+// Set parses and sets the value of a configuration key, creating a profile if necessary
+func (c *Config) Set(key string, value string) error {
+	if name, profileKey, ok := splitProfileKey(key); ok {
+		profile := c.Profiles[name]
+		field, err := fieldByKey(reflect.ValueOf(&profile).Elem(), profileKey, key)
+		if err != nil {
+			return err
+		}
+		err = setField(field, key, value)
+		if err != nil {
+			return err
+		}
+		if c.Profiles == nil {
+			c.Profiles = map[string]Profile{}
+		}
+		c.Profiles[name] = profile
+		return nil
+	}
+
+	field, err := fieldByKey(reflect.ValueOf(c).Elem(), key, key)
+	if err != nil {
+		return err
+	}
+	return setField(field, key, value)
+}
+
+func setField(field reflect.Value, key string, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %s for %s: expected true or false", value, key)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %s for %s: expected an integer", value, key)
+		}
+		field.SetInt(int64(i))
+	default:
+		return fmt.Errorf("cannot set key %s", key)
+	}
+	return nil
+}
+
+// Unset resets a configuration key to its zero value
+func (c *Config) Unset(key string) error {
+	if name, profileKey, ok := splitProfileKey(key); ok {
+		profile, exists := c.Profiles[name]
+		if !exists {
+			return fmt.Errorf("profile %s not found", name)
+		}
+		field, err := fieldByKey(reflect.ValueOf(&profile).Elem(), profileKey, key)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.Zero(field.Type()))
+		c.Profiles[name] = profile
+		return nil
+	}
+
+	field, err := fieldByKey(reflect.ValueOf(c).Elem(), key, key)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.Zero(field.Type()))
+	return nil
+}
+
+// fieldByKey returns the settable field of struct v corresponding to a dotted key, using fullKey
+// to report errors
+func fieldByKey(v reflect.Value, key string, fullKey string) (reflect.Value, error) {
+	var found reflect.Value
+	walkFields(v, "", func(k string, field reflect.Value) {
+		if k == key {
+			found = field
+		}
+	})
+	if !found.IsValid() {
+		return found, fmt.Errorf("unknown key %s, valid keys are: %s", fullKey, strings.Join(Keys(), ", "))
+	}
+	return found, nil
+}
+
+// splitProfileKey splits a key of the form profiles.<name>.<key> into the profile name and key
+func splitProfileKey(key string) (string, string, bool) {
+	if !strings.HasPrefix(key, keyPrefixProfiles) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, keyPrefixProfiles), ".", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// walkFields calls fn with the dotted key and value of every non-struct, non-map field of a struct,
+// using the toml tags of the fields as key components
+func walkFields(v reflect.Value, prefix string, fn func(key string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		if v.Field(i).Kind() == reflect.Map {
+			// Maps are accessed by the callers that need them
+			continue
+		}
+		if v.Field(i).Kind() == reflect.Struct {
+			walkFields(v.Field(i), key+".", fn)
+			continue
+		}
+		fn(key, v.Field(i))
+	}
+}
+
+// parseLegacy parses the contents b of the file fileName as unquoted "key = value" lines. Lines that
+// are not of this form or have an unknown key are skipped with a warning, without printing their values
+// as they may contain a token
+func parseLegacy(fileName string, b []byte) (*Config, error) {
+	conf := &Config{}
+	found := false
+
+	lineNum := 0
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lineParts := strings.SplitN(line, "=", 2)
+		if len(lineParts) != 2 {
+			fmt.Fprintf(os.Stderr, "warning: skipping line %d of %s, which is not of the form key = value\n", lineNum, fileName)
+			continue
+		}
+		key, ok := legacyKeys[strings.ToLower(strings.TrimSpace(lineParts[0]))]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "warning: skipping unknown key %s on line %d of %s\n", strings.TrimSpace(lineParts[0]), lineNum, fileName)
+			continue
+		}
+		err := conf.Set(key, strings.TrimSpace(lineParts[1]))
+		if err != nil {
+			return nil, err
+		}
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("no values found")
+	}
+
+	return conf, nil
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	tests := map[string]struct {
+		contents       string
+		expectedConfig *Config
+		expectedErr    bool
+	}{
+		"empty": {
+			contents:       "",
+			expectedConfig: &Config{},
+		},
+		"TOML": {
+			contents: `path = "/threads"
+token = "secret"
+profile = "work"
+
+[download]
+no_attachments = true
+video_quality = "best"
+blob_store = "hardlink"
+
+[network]
+host = "http://127.0.0.1:8080"
+insecure_skip_verify = true
+
+[oauth]
+client_id = "id"
+
+[profiles.work]
+path = "/work/threads"
+token = "work-secret"
+
+[profiles.home]
+css = "/home/thread.css"
+`,
+			expectedConfig: &Config{
+				Path:    "/threads",
+				Token:   "secret",
+				Profile: "work",
+				Download: Download{
+					NoAttachments: true,
+					VideoQuality:  "best",
+					BlobStore:     "hardlink",
+				},
+				Network: Network{
+					Host:               "http://127.0.0.1:8080",
+					InsecureSkipVerify: true,
+				},
+				OAuth: OAuth{ClientID: "id"},
+				Profiles: map[string]Profile{
+					"work": {Path: "/work/threads", Token: "work-secret"},
+					"home": {CSS: "/home/thread.css"},
+				},
+			},
+		},
+		"TOML with unknown key": {
+			contents:    "path = \"/threads\"\ncolor = \"blue\"\n",
+			expectedErr: true,
+		},
+		"legacy": {
+			contents: `# legacy configuration
+TOKEN = secret
+client_id=id
+host = http://127.0.0.1:8080
+
+insecure_skip_verify = true
+`,
+			expectedConfig: &Config{
+				Token: "secret",
+				Network: Network{
+					Host:               "http://127.0.0.1:8080",
+					InsecureSkipVerify: true,
+				},
+				OAuth: OAuth{ClientID: "id"},
+			},
+		},
+		"legacy with unknown key": {
+			contents: "token = secret\ncolor = blue\n",
+			expectedConfig: &Config{
+				Token: "secret",
+			},
+		},
+		"legacy with unrecognized line": {
+			contents: "path = /threads\nsave everything\ntoken = secret\n",
+			expectedConfig: &Config{
+				Path:  "/threads",
+				Token: "secret",
+			},
+		},
+		"legacy with invalid value": {
+			contents:    "token = secret\ninsecure_skip_verify = maybe\n",
+			expectedErr: true,
+		},
+		"legacy without values": {
+			contents:    "# nothing = here\n\n[",
+			expectedErr: true,
+		},
+		"invalid": {
+			contents:    "not a configuration file\n",
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(fileName, []byte(test.contents), 0o600); err != nil {
+				t.Fatalf("failed to write %s: %v", fileName, err)
+			}
+
+			conf, err := LoadFile(fileName)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", conf)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(conf, test.expectedConfig) {
+				t.Errorf("expected %+v, got %+v", test.expectedConfig, conf)
+			}
+		})
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	conf, err := LoadFile(filepath.Join(t.TempDir(), "config"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(conf, &Config{}) {
+		t.Errorf("expected empty config, got %+v", conf)
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	conf := &Config{
+		Path:     "/threads",
+		Download: Download{ImageSize: "orig"},
+		Profiles: map[string]Profile{"work": {Token: "work-secret"}},
+	}
+	fileName := filepath.Join(t.TempDir(), "thread-safe", "config")
+
+	if err := conf.SaveFile(fileName); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := LoadFile(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, conf) {
+		t.Errorf("expected %+v, got %+v", conf, loaded)
+	}
+	if ReadableByOthers(fileName) {
+		t.Errorf("expected %s to be readable only by its owner", fileName)
+	}
+}
+
+func TestGetSetUnset(t *testing.T) {
+	tests := map[string]struct {
+		key         string
+		value       string
+		expectedErr bool
+	}{
+		"top level":           {key: "path", value: "/threads"},
+		"section":             {key: "network.timeout", value: "30s"},
+		"bool":                {key: "download.no_attachments", value: "true"},
+		"profile":             {key: "profiles.work.token", value: "work-secret"},
+		"invalid bool":        {key: "network.insecure_skip_verify", value: "maybe", expectedErr: true},
+		"unknown key":         {key: "color", value: "blue", expectedErr: true},
+		"unknown section":     {key: "network.color", value: "blue", expectedErr: true},
+		"unknown profile key": {key: "profiles.work.color", value: "blue", expectedErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			conf := &Config{}
+			err := conf.Set(test.key, test.value)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			value, gErr := conf.Get(test.key)
+			if gErr != nil {
+				t.Fatalf("unexpected error: %v", gErr)
+			}
+			if value != test.value {
+				t.Errorf("expected %s, got %s", test.value, value)
+			}
+
+			if uErr := conf.Unset(test.key); uErr != nil {
+				t.Fatalf("unexpected error: %v", uErr)
+			}
+			unset, gErr := conf.Get(test.key)
+			if gErr != nil {
+				t.Fatalf("unexpected error: %v", gErr)
+			}
+			if unset != "" && unset != "false" {
+				t.Errorf("expected zero value, got %s", unset)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	conf := &Config{Profiles: map[string]Profile{"work": {}, "home": {}}}
+	keys := conf.Keys()
+
+	// Top level keys come first, followed by the keys of each profile in order of name
+	expectedProfileKeys := []string{
+		"profiles.home.path", "profiles.home.token", "profiles.home.template", "profiles.home.css",
+		"profiles.work.path", "profiles.work.token", "profiles.work.template", "profiles.work.css",
+	}
+	if len(keys) < len(expectedProfileKeys) {
+		t.Fatalf("expected at least %d keys, got %v", len(expectedProfileKeys), keys)
+	}
+	profileKeys := keys[len(keys)-len(expectedProfileKeys):]
+	if !reflect.DeepEqual(profileKeys, expectedProfileKeys) {
+		t.Errorf("expected profile keys %v, got %v", expectedProfileKeys, profileKeys)
+	}
+	for _, key := range keys {
+		if strings.Contains(key, "<name>") {
+			t.Errorf("expected no placeholder keys, got %s", key)
+		}
+		if _, err := conf.Get(key); err != nil {
+			t.Errorf("unexpected error getting %s: %v", key, err)
+		}
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	conf := &Config{
+		Path:    "/threads",
+		Token:   "secret",
+		CSS:     "/thread.css",
+		Profile: "work",
+		Profiles: map[string]Profile{
+			"work": {Path: "/work/threads"},
+			"home": {Token: "home-secret"},
+		},
+	}
+
+	tests := map[string]struct {
+		name          string
+		expectedPath  string
+		expectedToken string
+		expectedErr   bool
+	}{
+		"default profile": {
+			name:          "",
+			expectedPath:  "/work/threads",
+			expectedToken: "secret",
+		},
+		"named profile": {
+			name:          "home",
+			expectedPath:  "/threads",
+			expectedToken: "home-secret",
+		},
+		"unknown profile": {
+			name:        "school",
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			withProfile, err := conf.WithProfile(test.name)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if withProfile.Path != test.expectedPath {
+				t.Errorf("expected path %s, got %s", test.expectedPath, withProfile.Path)
+			}
+			if withProfile.Token != test.expectedToken {
+				t.Errorf("expected token %s, got %s", test.expectedToken, withProfile.Token)
+			}
+			if withProfile.CSS != conf.CSS {
+				t.Errorf("expected css %s, got %s", conf.CSS, withProfile.CSS)
+			}
+		})
+	}
+}
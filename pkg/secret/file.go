@@ -0,0 +1,181 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// saltSize is the size in bytes of the random salt used to derive the encryption key
+	saltSize = 16
+	// keySize is the size in bytes of the derived AES-256 key
+	keySize = 32
+
+	// scrypt cost parameters recommended for interactive use
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// PassphraseFunc returns the passphrase used to encrypt a File, asking for confirmation if confirm is
+// true because the passphrase is being chosen for a new file
+type PassphraseFunc func(confirm bool) ([]byte, error)
+
+// File is a Store that keeps secrets in a single file encrypted with AES-GCM using a key derived from
+// a passphrase with scrypt
+type File struct {
+	fileName   string
+	passphrase PassphraseFunc
+}
+
+// NewFile constructs a File using the provided path and passphrase source
+func NewFile(fileName string, passphrase PassphraseFunc) *File {
+	return &File{
+		fileName:   filepath.Clean(fileName),
+		passphrase: passphrase,
+	}
+}
+
+// Name describes where a File keeps its secrets
+func (f *File) Name() string {
+	return fmt.Sprintf("encrypted file %s", f.fileName)
+}
+
+// Get returns the secret saved for key or ErrNotFound if none exists
+func (f *File) Get(key string) (string, error) {
+	secrets, _, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set saves a secret for key, replacing any existing value
+func (f *File) Set(key string, value string) error {
+	secrets, passphrase, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[key] = value
+	return f.save(secrets, passphrase)
+}
+
+// Delete removes the secret saved for key, returning ErrNotFound if none exists
+func (f *File) Delete(key string) error {
+	secrets, passphrase, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(secrets, key)
+	if len(secrets) == 0 {
+		return os.Remove(f.fileName)
+	}
+	return f.save(secrets, passphrase)
+}
+
+// load reads and decrypts the secrets saved in a File, returning the passphrase so that it need not be
+// requested again to save changes
+func (f *File) load() (map[string]string, []byte, error) {
+	secrets := map[string]string{}
+
+	b, err := os.ReadFile(f.fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("failed to read secrets file: %w", err)
+		}
+		// A missing file has no secrets and takes a new passphrase, which is only requested when saving
+		return secrets, nil, nil
+	}
+
+	passphrase, pErr := f.passphrase(false)
+	if pErr != nil {
+		return nil, nil, pErr
+	}
+	if len(b) < saltSize {
+		return nil, nil, errors.New("secrets file is corrupt")
+	}
+	gcm, gErr := newGCM(passphrase, b[:saltSize])
+	if gErr != nil {
+		return nil, nil, gErr
+	}
+	b = b[saltSize:]
+	if len(b) < gcm.NonceSize() {
+		return nil, nil, errors.New("secrets file is corrupt")
+	}
+	plaintext, oErr := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if oErr != nil {
+		return nil, nil, errors.New("failed to decrypt secrets file, the passphrase may be incorrect")
+	}
+
+	jErr := json.Unmarshal(plaintext, &secrets)
+	if jErr != nil {
+		return nil, nil, fmt.Errorf("failed to parse secrets file: %w", jErr)
+	}
+	return secrets, passphrase, nil
+}
+
+// save encrypts and writes secrets with a fresh salt and nonce, requesting a new passphrase if one is
+// not provided
+func (f *File) save(secrets map[string]string, passphrase []byte) error {
+	if passphrase == nil {
+		p, err := f.passphrase(true)
+		if err != nil {
+			return err
+		}
+		passphrase = p
+	}
+	if len(passphrase) == 0 {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltSize)
+	_, sErr := io.ReadFull(rand.Reader, salt)
+	if sErr != nil {
+		return sErr
+	}
+	gcm, gErr := newGCM(passphrase, salt)
+	if gErr != nil {
+		return gErr
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, nErr := io.ReadFull(rand.Reader, nonce)
+	if nErr != nil {
+		return nErr
+	}
+
+	out := append(salt, gcm.Seal(nonce, nonce, plaintext, nil)...)
+	return os.WriteFile(f.fileName, out, 0o600)
+}
+
+func newGCM(passphrase []byte, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	block, bErr := aes.NewCipher(key)
+	if bErr != nil {
+		return nil, bErr
+	}
+	return cipher.NewGCM(block)
+}
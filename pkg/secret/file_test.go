@@ -0,0 +1,146 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func passphrase(p string) PassphraseFunc {
+	return func(_ bool) ([]byte, error) {
+		return []byte(p), nil
+	}
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "secrets")
+	f := NewFile(fileName, passphrase("correct horse"))
+
+	if _, err := f.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before saving, got %v", err)
+	}
+	if err := f.Set("token", "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Set("work/token", "work-secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A new File reads the secrets saved by another
+	reopened := NewFile(fileName, passphrase("correct horse"))
+	for key, expected := range map[string]string{"token": "secret", "work/token": "work-secret"} {
+		value, err := reopened.Get(key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if value != expected {
+			t.Errorf("expected %s, got %s", expected, value)
+		}
+	}
+
+	info, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("expected permissions 0600, got %#o", perm)
+	}
+
+	if dErr := reopened.Delete("token"); dErr != nil {
+		t.Fatalf("unexpected error: %v", dErr)
+	}
+	if _, gErr := f.Get("token"); !errors.Is(gErr, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after deleting, got %v", gErr)
+	}
+	if dErr := f.Delete("token"); !errors.Is(dErr, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", dErr)
+	}
+
+	// Deleting the last secret removes the file
+	if dErr := f.Delete("work/token"); dErr != nil {
+		t.Fatalf("unexpected error: %v", dErr)
+	}
+	if _, sErr := os.Stat(fileName); !os.IsNotExist(sErr) {
+		t.Errorf("expected file removed, got %v", sErr)
+	}
+}
+
+func TestFileLoadErrors(t *testing.T) {
+	saved := func(t *testing.T, fileName string) []byte {
+		t.Helper()
+		if err := NewFile(fileName, passphrase("correct horse")).Set("token", "secret"); err != nil {
+			t.Fatalf("failed to save secret: %v", err)
+		}
+		b, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", fileName, err)
+		}
+		return b
+	}
+
+	tests := map[string]struct {
+		// contents returns the contents to overwrite the file with given the contents of a valid file,
+		// or nil to keep them
+		contents   func(b []byte) []byte
+		passphrase string
+	}{
+		"wrong passphrase": {
+			contents:   func(_ []byte) []byte { return nil },
+			passphrase: "battery staple",
+		},
+		"empty": {
+			contents:   func(_ []byte) []byte { return []byte{} },
+			passphrase: "correct horse",
+		},
+		"truncated salt": {
+			contents:   func(b []byte) []byte { return b[:saltSize-1] },
+			passphrase: "correct horse",
+		},
+		"truncated nonce": {
+			contents:   func(b []byte) []byte { return b[:saltSize+4] },
+			passphrase: "correct horse",
+		},
+		"truncated ciphertext": {
+			contents:   func(b []byte) []byte { return b[:len(b)-1] },
+			passphrase: "correct horse",
+		},
+		"corrupt ciphertext": {
+			contents: func(b []byte) []byte {
+				b[len(b)-1] ^= 0xff
+				return b
+			},
+			passphrase: "correct horse",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "secrets")
+			if contents := test.contents(saved(t, fileName)); contents != nil {
+				if err := os.WriteFile(fileName, contents, 0o600); err != nil {
+					t.Fatalf("failed to write %s: %v", fileName, err)
+				}
+			}
+
+			f := NewFile(fileName, passphrase(test.passphrase))
+			if _, err := f.Get("token"); err == nil || errors.Is(err, ErrNotFound) {
+				t.Errorf("expected error reading secret, got %v", err)
+			}
+			// A file that cannot be read is not overwritten
+			if err := f.Set("token", "other"); err == nil {
+				t.Error("expected error saving secret")
+			}
+		})
+	}
+}
+
+func TestFileEmptyPassphrase(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "secrets")
+	if err := NewFile(fileName, passphrase("")).Set("token", "secret"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("expected no file written, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package secret
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyProbe is a key that is never set, used to check if the OS secret store can be reached
+const keyProbe = "availability-check"
+
+// Keyring is a Store backed by the OS secret store, which is the Secret Service API over D-Bus on
+// Linux, the Keychain on macOS, and the Credential Manager on Windows
+type Keyring struct{}
+
+// NewKeyring constructs a Keyring
+func NewKeyring() *Keyring {
+	return &Keyring{}
+}
+
+// Name describes where a Keyring keeps its secrets
+func (k *Keyring) Name() string {
+	return "OS secret store"
+}
+
+// Get returns the secret saved for key or ErrNotFound if none exists
+func (k *Keyring) Get(key string) (string, error) {
+	value, err := keyring.Get(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+// Set saves a secret for key, replacing any existing value
+func (k *Keyring) Set(key string, value string) error {
+	return keyring.Set(service, key, value)
+}
+
+// Delete removes the secret saved for key, returning ErrNotFound if none exists
+func (k *Keyring) Delete(key string) error {
+	err := keyring.Delete(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// keyringAvailable returns an error if the OS secret store cannot be used, such as when no Secret
+// Service provider is running on the session bus
+func keyringAvailable() error {
+	_, err := keyring.Get(service, keyProbe)
+	if err == nil || errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+// Package secret stores sensitive values such as the Twitter API bearer token outside of the
+// plaintext configuration file
+package secret
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// BackendAuto selects the OS secret store if it is available and an encrypted file otherwise
+	BackendAuto = "auto"
+	// BackendKeyring selects the OS secret store (Secret Service over D-Bus on Linux)
+	BackendKeyring = "keyring"
+	// BackendFile selects a file encrypted with a passphrase
+	BackendFile = "file"
+
+	// service is the name under which secrets are kept in the OS secret store
+	service = "thread-safe"
+)
+
+// ErrNotFound is returned when a Store does not contain a secret for the requested key
+var ErrNotFound = errors.New("secret not found")
+
+// Store is the interface for saving and retrieving secrets
+type Store interface {
+	// Name describes where a Store keeps its secrets
+	Name() string
+	// Get returns the secret saved for key or ErrNotFound if none exists
+	Get(key string) (string, error)
+	// Set saves a secret for key, replacing any existing value
+	Set(key string, value string) error
+	// Delete removes the secret saved for key, returning ErrNotFound if none exists
+	Delete(key string) error
+}
+
+// New constructs the Store for the named backend, using fileName and passphrase for the encrypted
+// file backend
+func New(backend string, fileName string, passphrase PassphraseFunc) (Store, error) {
+	switch backend {
+	case BackendKeyring:
+		if err := keyringAvailable(); err != nil {
+			return nil, fmt.Errorf("OS secret store is not available: %w", err)
+		}
+		return NewKeyring(), nil
+	case BackendFile:
+		return NewFile(fileName, passphrase), nil
+	case BackendAuto, "":
+		if keyringAvailable() == nil {
+			return NewKeyring(), nil
+		}
+		return NewFile(fileName, passphrase), nil
+	default:
+		return nil, fmt.Errorf("unknown secret store %s, must be one of %s, %s, or %s", backend, BackendAuto, BackendKeyring, BackendFile)
+	}
+}
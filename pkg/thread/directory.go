@@ -29,6 +29,11 @@ func (d *Directory) Exists() bool {
 	return !os.IsNotExist(err)
 }
 
+// Remove removes a Directory and all of its contents
+func (d *Directory) Remove() error {
+	return os.RemoveAll(d.path)
+}
+
 // SubDir constructs a file path by joining any provided subpath strings to the Directory path and
 // returns the path and a bool indicating if the constructed path exists
 func (d *Directory) SubDir(subpaths ...string) (string, bool) {
@@ -3,9 +3,14 @@ package thread
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"text/template"
+
+	"github.com/dkaslovsky/thread-safe/pkg/blobstore"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
 )
 
 const (
@@ -45,6 +50,26 @@ func FromJSON(appDir string, threadName string) (*Thread, error) {
 	return &th, nil
 }
 
+// ListNames returns the names of the directories under the top level path that contain a thread's
+// JSON file
+func ListNames(topLevelDir string) ([]string, error) {
+	entries, err := os.ReadDir(topLevelDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, exists := NewDirectory(topLevelDir, entry.Name()).SubDir(fileNameJSON); exists {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
 // ToJSON generates and saves a JSON file from a Thread's tweets
 func (th *Thread) ToJSON() error {
 	b, err := json.Marshal(th)
@@ -82,26 +107,134 @@ func (th *Thread) ToHTML(templateFileName string, cssFileName string) error {
 	return nil
 }
 
-// DownloadAttachments saves all media attachments from a Thread's
-func (th *Thread) DownloadAttachments() error {
+// DownloadAttachments saves all media attachments from a Thread's tweets using the provided HTTP client,
+// requesting images at the specified size, along with thumbnails of images and posters of videos
+func (th *Thread) DownloadAttachments(client *http.Client, imageSize twitter.ImageSize) error {
 	attachmentDir := NewDirectory(th.Dir.Join(dirNameAttachments), "")
 	err := attachmentDir.Create()
 	if err != nil {
 		return err
 	}
 
-	for _, tweet := range th.Tweets {
-		for _, attachment := range tweet.Attachments {
-			err := attachment.Download(attachmentDir.Join(attachment.Name(tweet.ID)))
+	for _, tweet := range th.allTweets() {
+		for i := range tweet.Attachments {
+			attachment := &tweet.Attachments[i]
+			err := attachment.Download(client, attachmentDir.String(), tweet.ID, imageSize)
+			if err != nil {
+				return err
+			}
+			// Thumbnails and posters are optional as the template falls back to the full media
+			tErr := generateThumbnail(attachmentDir, tweet.ID, *attachment)
+			if tErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to generate thumbnail for media_key %s: %v\n", attachment.MediaKey, tErr)
+			}
+			pErr := downloadPoster(client, attachmentDir, tweet.ID, *attachment)
+			if pErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to save poster for media_key %s: %v\n", attachment.MediaKey, pErr)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DownloadAvatar saves the profile image of a Thread's author using the provided HTTP client, so that
+// the author's profile can be displayed even if their account is deleted
+func (th *Thread) DownloadAvatar(client *http.Client) error {
+	if th.Author == nil {
+		return nil
+	}
+	attachmentDir := NewDirectory(th.Dir.Join(dirNameAttachments), "")
+	err := attachmentDir.Create()
+	if err != nil {
+		return err
+	}
+	return th.Author.DownloadAvatar(client, attachmentDir.String())
+}
+
+// CopyAttachments saves all media attachments from a Thread's tweets by copying local files, using
+// srcFile to return the path to an attachment's file and a bool indicating if that file exists, and
+// records the size and digest of each copied file and generates thumbnails of images
+func (th *Thread) CopyAttachments(srcFile func(tweetID string, attachment twitter.Attachment) (string, bool)) error {
+	attachmentDir := NewDirectory(th.Dir.Join(dirNameAttachments), "")
+	err := attachmentDir.Create()
+	if err != nil {
+		return err
+	}
+
+	for _, tweet := range th.allTweets() {
+		for i := range tweet.Attachments {
+			attachment := &tweet.Attachments[i]
+			src, exists := srcFile(tweet.ID, *attachment)
+			if !exists {
+				continue
+			}
+			// Name the copy by the MIME type of its contents, as for downloaded attachments
+			mErr := attachment.DetectMIMEType(src)
+			if mErr != nil {
+				return mErr
+			}
+			dst := attachmentDir.Join(attachment.Name(tweet.ID))
+			err := copyFile(src, dst)
 			if err != nil {
 				return err
 			}
+			rErr := attachment.Record(dst)
+			if rErr != nil {
+				return rErr
+			}
+			tErr := generateThumbnail(attachmentDir, tweet.ID, *attachment)
+			if tErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to generate thumbnail for media_key %s: %v\n", attachment.MediaKey, tErr)
+			}
 		}
 	}
 
 	return nil
 }
 
+// StoreAttachments moves a Thread's attachment files into a blob store shared by all threads, replacing
+// them with links to the stored files
+func (th *Thread) StoreAttachments(store *blobstore.Store) error {
+	attachmentDir := NewDirectory(th.Dir.Join(dirNameAttachments), "")
+
+	for _, tweet := range th.allTweets() {
+		for _, attachment := range tweet.Attachments {
+			fileName, exists := attachmentDir.SubDir(attachment.Name(tweet.ID))
+			if !exists {
+				continue
+			}
+			err := store.Add(fileName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return store.Save()
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, oErr := os.Create(filepath.Clean(dst))
+	if oErr != nil {
+		return oErr
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	_, cErr := io.Copy(out, in)
+	return cErr
+}
+
 func loadHTMLTemplateFile(threadDir *Directory, templateFileName string) (string, error) {
 	if templateFileName != "" {
 		return readFile(templateFileName)
@@ -0,0 +1,130 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// DefaultMaxLength is the default maximum number of tweets to be fetched for constructing a thread
+const DefaultMaxLength = 100
+
+const (
+	// Continue includes a tweet in a thread and continues to the tweet it replies to
+	Continue Decision = iota
+	// Last includes a tweet in a thread as the first tweet of its chain
+	Last
+	// Stop ends a thread's chain without including a tweet
+	Stop
+)
+
+// Decision is the outcome of evaluating a fetched tweet with a StopPolicy
+type Decision int
+
+// StopPolicy decides where the chain of a thread's tweets ends while it is walked from its last tweet.
+// Decide is called with the starting tweet of the walk, a fetched tweet in the starting tweet's
+// conversation, and the number of tweets already included in the thread, and returns an error to abort
+// the walk.
+type StopPolicy interface {
+	Decide(start *twitter.Tweet, tweet *twitter.Tweet, count int) (Decision, error)
+}
+
+// StopPolicyFunc adapts a function to a StopPolicy
+type StopPolicyFunc func(start *twitter.Tweet, tweet *twitter.Tweet, count int) (Decision, error)
+
+// Decide calls f
+func (f StopPolicyFunc) Decide(start *twitter.Tweet, tweet *twitter.Tweet, count int) (Decision, error) {
+	return f(start, tweet, count)
+}
+
+// DefaultStopPolicy returns the StopPolicy following a single author's tweets, failing for threads
+// longer than DefaultMaxLength
+func DefaultStopPolicy() StopPolicy {
+	return AllOf(SingleAuthor(), MaxLength(DefaultMaxLength))
+}
+
+// AllOf returns a StopPolicy combining policies, which returns the first error of the policies or
+// otherwise their most restrictive decision
+func AllOf(policies ...StopPolicy) StopPolicy {
+	return StopPolicyFunc(func(start *twitter.Tweet, tweet *twitter.Tweet, count int) (Decision, error) {
+		decision := Continue
+		for _, policy := range policies {
+			d, err := policy.Decide(start, tweet, count)
+			if err != nil {
+				return Stop, err
+			}
+			if d > decision {
+				decision = d
+			}
+		}
+		return decision, nil
+	})
+}
+
+// SingleAuthor returns a StopPolicy that ends a thread at a tweet by an author other than the author of
+// the starting tweet
+func SingleAuthor() StopPolicy {
+	return StopPolicyFunc(func(start *twitter.Tweet, tweet *twitter.Tweet, _ int) (Decision, error) {
+		if tweet.AuthorID != start.AuthorID {
+			return Stop, nil
+		}
+		return Continue, nil
+	})
+}
+
+// AllowedAuthors returns a StopPolicy that ends a thread at a tweet by an author other than the author of
+// the starting tweet and the authors with the provided handles, which are matched case-insensitively
+// with or without a leading "@"
+func AllowedAuthors(handles ...string) StopPolicy {
+	allowed := map[string]struct{}{}
+	for _, handle := range handles {
+		allowed[normalizeHandle(handle)] = struct{}{}
+	}
+	return StopPolicyFunc(func(start *twitter.Tweet, tweet *twitter.Tweet, _ int) (Decision, error) {
+		if tweet.AuthorID == start.AuthorID {
+			return Continue, nil
+		}
+		if _, ok := allowed[normalizeHandle(tweet.AuthorHandle)]; ok {
+			return Continue, nil
+		}
+		return Stop, nil
+	})
+}
+
+// AnyAuthor returns a StopPolicy that follows tweets by any author
+func AnyAuthor() StopPolicy {
+	return StopPolicyFunc(func(_ *twitter.Tweet, _ *twitter.Tweet, _ int) (Decision, error) {
+		return Continue, nil
+	})
+}
+
+// StopAtTweet returns a StopPolicy that ends a thread's chain at the tweet with the provided ID,
+// including it as the chain's first tweet, and at any earlier tweet so that side branches also end there
+func StopAtTweet(id string) StopPolicy {
+	return StopPolicyFunc(func(_ *twitter.Tweet, tweet *twitter.Tweet, _ int) (Decision, error) {
+		if tweet.ID == id {
+			return Last, nil
+		}
+		if twitter.LessID(tweet.ID, id) {
+			return Stop, nil
+		}
+		return Continue, nil
+	})
+}
+
+// MaxLength returns a StopPolicy that fails for threads with more than limit tweets, including the
+// tweets of side branches
+func MaxLength(limit int) StopPolicy {
+	return StopPolicyFunc(func(_ *twitter.Tweet, _ *twitter.Tweet, count int) (Decision, error) {
+		if count >= limit {
+			return Stop, fmt.Errorf("exceeded maximum number of tweets to fetch [%d]", limit)
+		}
+		return Continue, nil
+	})
+}
+
+// normalizeHandle returns a user's handle without a leading "@" in lowercase
+func normalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
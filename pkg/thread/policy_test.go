@@ -0,0 +1,148 @@
+package thread
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+func TestStopPolicies(t *testing.T) {
+	start := &twitter.Tweet{ID: "1700000000000000009", AuthorID: "1234567890", AuthorHandle: "thread_safe_dev"}
+	sameAuthor := &twitter.Tweet{ID: "1700000000000000007", AuthorID: "1234567890", AuthorHandle: "thread_safe_dev"}
+	otherAuthor := &twitter.Tweet{ID: "1700000000000000008", AuthorID: "2345678901", AuthorHandle: "thread_safe_docs"}
+
+	errFailed := errors.New("failed")
+	failing := StopPolicyFunc(func(_ *twitter.Tweet, _ *twitter.Tweet, _ int) (Decision, error) {
+		return Continue, errFailed
+	})
+	decide := func(d Decision) StopPolicy {
+		return StopPolicyFunc(func(_ *twitter.Tweet, _ *twitter.Tweet, _ int) (Decision, error) {
+			return d, nil
+		})
+	}
+
+	tests := map[string]struct {
+		policy           StopPolicy
+		tweet            *twitter.Tweet
+		count            int
+		expectedDecision Decision
+		expectedErr      bool
+	}{
+		"single author continues at same author": {
+			policy:           SingleAuthor(),
+			tweet:            sameAuthor,
+			expectedDecision: Continue,
+		},
+		"single author stops at other author": {
+			policy:           SingleAuthor(),
+			tweet:            otherAuthor,
+			expectedDecision: Stop,
+		},
+		"allowed authors continues at allowed author": {
+			policy:           AllowedAuthors("@Thread_Safe_Docs"),
+			tweet:            otherAuthor,
+			expectedDecision: Continue,
+		},
+		"allowed authors stops at other author": {
+			policy:           AllowedAuthors("someone_else"),
+			tweet:            otherAuthor,
+			expectedDecision: Stop,
+		},
+		"any author continues at other author": {
+			policy:           AnyAuthor(),
+			tweet:            otherAuthor,
+			expectedDecision: Continue,
+		},
+		"stop at tweet continues at later tweet": {
+			policy:           StopAtTweet("1700000000000000007"),
+			tweet:            otherAuthor,
+			expectedDecision: Continue,
+		},
+		"stop at tweet includes tweet as last": {
+			policy:           StopAtTweet("1700000000000000007"),
+			tweet:            sameAuthor,
+			expectedDecision: Last,
+		},
+		"stop at tweet stops at earlier tweet": {
+			policy:           StopAtTweet("1700000000000000008"),
+			tweet:            sameAuthor,
+			expectedDecision: Stop,
+		},
+		"max length continues below limit": {
+			policy:           MaxLength(3),
+			tweet:            sameAuthor,
+			count:            2,
+			expectedDecision: Continue,
+		},
+		"max length fails at limit": {
+			policy:      MaxLength(3),
+			tweet:       sameAuthor,
+			count:       3,
+			expectedErr: true,
+		},
+		"all of continues if all continue": {
+			policy:           AllOf(SingleAuthor(), MaxLength(3)),
+			tweet:            sameAuthor,
+			expectedDecision: Continue,
+		},
+		"all of uses most restrictive decision": {
+			policy:           AllOf(decide(Last), decide(Stop), decide(Continue)),
+			tweet:            sameAuthor,
+			expectedDecision: Stop,
+		},
+		"all of uses last over continue": {
+			policy:           AllOf(decide(Continue), decide(Last)),
+			tweet:            sameAuthor,
+			expectedDecision: Last,
+		},
+		"all of fails if any policy fails": {
+			policy:      AllOf(decide(Stop), failing),
+			tweet:       sameAuthor,
+			expectedErr: true,
+		},
+		"all of without policies continues": {
+			policy:           AllOf(),
+			tweet:            otherAuthor,
+			expectedDecision: Continue,
+		},
+		"default stops at other author": {
+			policy:           DefaultStopPolicy(),
+			tweet:            otherAuthor,
+			expectedDecision: Stop,
+		},
+		"default fails at maximum length": {
+			policy:      DefaultStopPolicy(),
+			tweet:       sameAuthor,
+			count:       DefaultMaxLength,
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			decision, err := test.policy.Decide(start, test.tweet, test.count)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decision != test.expectedDecision {
+				t.Errorf("expected decision %d, got %d", test.expectedDecision, decision)
+			}
+		})
+	}
+}
+
+func TestLoadMaxLength(t *testing.T) {
+	th := New(t.TempDir(), "thread")
+	err := th.Load(twittertest.NewClient(t, fixturesNathanMacKinnon), "969990907490484225", AllOf(SingleAuthor(), MaxLength(5)))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
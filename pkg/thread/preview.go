@@ -0,0 +1,84 @@
+package thread
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/dkaslovsky/thread-safe/pkg/thumbnail"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// thumbnailWidth is the width in pixels of thumbnails and posters, twice the width at which the default
+// template displays attachments so that they remain sharp on high density displays
+const thumbnailWidth = 640
+
+// generateThumbnail saves a downscaled copy of an image attachment if it is wider than thumbnailWidth,
+// removing any previous thumbnail otherwise and skipping thumbnails that are newer than their image.
+// Images that are not downloaded or are in an unsupported format are skipped, as the template falls back
+// to the full size image.
+func generateThumbnail(attachmentDir *Directory, tweetID string, attachment twitter.Attachment) error {
+	if attachment.Type != twitter.MediaTypePhoto {
+		return nil
+	}
+	src, exists := attachmentDir.SubDir(attachment.Name(tweetID))
+	if !exists {
+		return nil
+	}
+	dst := attachmentDir.Join(attachment.ThumbnailName(tweetID))
+	if isNewer(dst, src) {
+		return nil
+	}
+
+	created, err := thumbnail.Create(src, dst, thumbnailWidth)
+	if errors.Is(err, thumbnail.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !created {
+		rErr := os.Remove(dst)
+		if rErr != nil && !os.IsNotExist(rErr) {
+			return rErr
+		}
+	}
+	return nil
+}
+
+// downloadPoster saves a downscaled copy of the preview image of a video attachment as its poster,
+// skipping videos without a preview image
+func downloadPoster(client *http.Client, attachmentDir *Directory, tweetID string, attachment twitter.Attachment) error {
+	if attachment.Type == twitter.MediaTypePhoto {
+		return nil
+	}
+	tmpFileName, err := attachment.DownloadPreviewImage(client, attachmentDir.String())
+	if err != nil {
+		return err
+	}
+	if tmpFileName == "" {
+		return nil
+	}
+	defer func() {
+		_ = os.Remove(tmpFileName)
+	}()
+
+	cErr := thumbnail.Convert(tmpFileName, attachmentDir.Join(attachment.PosterName(tweetID)), thumbnailWidth)
+	if errors.Is(cErr, thumbnail.ErrUnsupported) {
+		return nil
+	}
+	return cErr
+}
+
+// isNewer evaluates if a file exists and was modified after another file
+func isNewer(fileName string, otherFileName string) bool {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return false
+	}
+	otherInfo, oErr := os.Stat(otherFileName)
+	if oErr != nil {
+		return false
+	}
+	return info.ModTime().After(otherInfo.ModTime())
+}
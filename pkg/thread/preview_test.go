@@ -0,0 +1,253 @@
+package thread
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+func TestGenerateThumbnail(t *testing.T) {
+	tests := map[string]struct {
+		attachmentType string
+		// image is the contents of the attachment file, which is not saved if nil
+		image []byte
+		// staleThumbnail is whether a thumbnail older than the image exists
+		staleThumbnail    bool
+		expectedThumbnail bool
+	}{
+		"wide image": {
+			attachmentType:    twitter.MediaTypePhoto,
+			image:             encodePNG(t, 1200, 675),
+			expectedThumbnail: true,
+		},
+		"narrow image": {
+			attachmentType:    twitter.MediaTypePhoto,
+			image:             encodePNG(t, 320, 180),
+			expectedThumbnail: false,
+		},
+		"narrow image replacing stale thumbnail": {
+			attachmentType:    twitter.MediaTypePhoto,
+			image:             encodePNG(t, 320, 180),
+			staleThumbnail:    true,
+			expectedThumbnail: false,
+		},
+		"image not downloaded": {
+			attachmentType:    twitter.MediaTypePhoto,
+			expectedThumbnail: false,
+		},
+		"unsupported format": {
+			attachmentType:    twitter.MediaTypePhoto,
+			image:             []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+			expectedThumbnail: false,
+		},
+		"video": {
+			attachmentType:    twitter.MediaTypeVideo,
+			image:             encodePNG(t, 1200, 675),
+			expectedThumbnail: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			attachmentDir := NewDirectory(t.TempDir(), "")
+			attachment := twitter.Attachment{
+				MediaKey: "3_1",
+				Type:     test.attachmentType,
+				URL:      "https://pbs.twimg.com/media/image.png",
+			}
+			thumb := attachmentDir.Join(attachment.ThumbnailName("1"))
+			if test.staleThumbnail {
+				writeTestFile(t, thumb, []byte("stale"))
+				stale := time.Now().Add(-time.Hour)
+				if err := os.Chtimes(thumb, stale, stale); err != nil {
+					t.Fatalf("failed to set modification time of %s: %v", thumb, err)
+				}
+			}
+			if test.image != nil {
+				writeTestFile(t, attachmentDir.Join(attachment.Name("1")), test.image)
+			}
+
+			if err := generateThumbnail(attachmentDir, "1", attachment); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err := os.Stat(thumb)
+			if exists := err == nil; exists != test.expectedThumbnail {
+				t.Errorf("expected thumbnail %t, got %v", test.expectedThumbnail, err)
+			}
+		})
+	}
+}
+
+func TestGenerateThumbnailSkipsNewerThumbnail(t *testing.T) {
+	attachmentDir := NewDirectory(t.TempDir(), "")
+	attachment := twitter.Attachment{MediaKey: "3_1", Type: twitter.MediaTypePhoto, URL: "https://pbs.twimg.com/media/image.png"}
+	src := attachmentDir.Join(attachment.Name("1"))
+	writeTestFile(t, src, encodePNG(t, 1200, 675))
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(src, past, past); err != nil {
+		t.Fatalf("failed to set modification time of %s: %v", src, err)
+	}
+	thumb := attachmentDir.Join(attachment.ThumbnailName("1"))
+	writeTestFile(t, thumb, []byte("existing"))
+
+	if err := generateThumbnail(attachmentDir, "1", attachment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ := os.ReadFile(thumb); string(b) != "existing" {
+		t.Error("expected thumbnail newer than its image to be kept")
+	}
+}
+
+func TestDownloadPoster(t *testing.T) {
+	preview := encodePNG(t, 1280, 720)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/preview.png":
+			_, _ = w.Write(preview)
+		case "/invalid.png":
+			_, _ = w.Write([]byte("not an image"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := map[string]struct {
+		attachmentType  string
+		previewImageURL string
+		expectedPoster  bool
+		expectedErr     bool
+	}{
+		"video": {
+			attachmentType:  twitter.MediaTypeVideo,
+			previewImageURL: server.URL + "/preview.png",
+			expectedPoster:  true,
+		},
+		"GIF": {
+			attachmentType:  twitter.MediaTypeGIF,
+			previewImageURL: server.URL + "/preview.png",
+			expectedPoster:  true,
+		},
+		"photo": {
+			attachmentType:  twitter.MediaTypePhoto,
+			previewImageURL: server.URL + "/preview.png",
+			expectedPoster:  false,
+		},
+		"no preview image": {
+			attachmentType: twitter.MediaTypeVideo,
+			expectedPoster: false,
+		},
+		"unavailable preview image": {
+			attachmentType:  twitter.MediaTypeVideo,
+			previewImageURL: server.URL + "/missing.png",
+			expectedErr:     true,
+		},
+		"unsupported preview image": {
+			attachmentType:  twitter.MediaTypeVideo,
+			previewImageURL: server.URL + "/invalid.png",
+			expectedPoster:  false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			attachmentDir := NewDirectory(t.TempDir(), "")
+			attachment := twitter.Attachment{
+				MediaKey:        "7_1",
+				Type:            test.attachmentType,
+				PreviewImageURL: test.previewImageURL,
+			}
+
+			err := downloadPoster(server.Client(), attachmentDir, "1", attachment)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			entries, rErr := os.ReadDir(attachmentDir.String())
+			if rErr != nil {
+				t.Fatalf("unexpected error: %v", rErr)
+			}
+			if !test.expectedPoster {
+				// Temporary files are removed whether or not the poster is saved
+				if len(entries) != 0 {
+					t.Errorf("expected no files, got %d", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].Name() != attachment.PosterName("1") {
+				t.Errorf("expected only poster %s, got %v", attachment.PosterName("1"), entries)
+			}
+		})
+	}
+}
+
+func TestDownloadAttachmentsWithoutPreviews(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/video.mp4":
+			_, _ = w.Write([]byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"))
+		case "/image.png":
+			_, _ = w.Write([]byte("not an image"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	th := New(t.TempDir(), "thread")
+	th.Tweets = []*twitter.Tweet{{
+		ID: "1",
+		Attachments: []twitter.Attachment{
+			{MediaKey: "7_1", Type: twitter.MediaTypeVideo, URL: server.URL + "/video.mp4", PreviewImageURL: server.URL + "/missing.jpg"},
+			{MediaKey: "3_2", Type: twitter.MediaTypePhoto, URL: server.URL + "/image.png"},
+		},
+	}}
+
+	// Failing to create a poster or thumbnail does not fail the download of the media itself
+	if err := th.DownloadAttachments(server.Client(), twitter.ImageSizeDefault); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, attachment := range th.Tweets[0].Attachments {
+		if _, err := os.Stat(th.Dir.Join(dirNameAttachments, attachment.Name("1"))); err != nil {
+			t.Errorf("expected attachment %s saved: %v", attachment.MediaKey, err)
+		}
+	}
+}
+
+func encodePNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, fileName string, b []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o750); err != nil {
+		t.Fatalf("failed to create %s: %v", filepath.Dir(fileName), err)
+	}
+	if err := os.WriteFile(fileName, b, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", fileName, err)
+	}
+}
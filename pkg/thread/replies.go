@@ -0,0 +1,138 @@
+package thread
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+const (
+	// RepliesNone disables saving replies from other users
+	RepliesNone ReplyDepth = 0
+	// RepliesAll saves replies at any depth
+	RepliesAll ReplyDepth = -1
+)
+
+// ReplyDepth is the number of levels of replies below a Thread's tweets to save, which implements
+// flag.Value as a boolean flag so that passing it without a value saves replies at any depth
+type ReplyDepth int
+
+// ParseReplyDepth parses a ReplyDepth from "true" or "all" for any depth, "false" or an empty string
+// for no replies, or a number of levels
+func ParseReplyDepth(s string) (ReplyDepth, error) {
+	switch s {
+	case "", "false":
+		return RepliesNone, nil
+	case "true", "all":
+		return RepliesAll, nil
+	}
+	depth, err := strconv.Atoi(s)
+	if err != nil || depth < 0 {
+		return RepliesNone, fmt.Errorf("invalid reply depth %s: expected all or a non-negative number of levels", s)
+	}
+	return ReplyDepth(depth), nil
+}
+
+// String returns the string representation of a ReplyDepth
+func (d ReplyDepth) String() string {
+	switch d {
+	case RepliesNone:
+		return "false"
+	case RepliesAll:
+		return "all"
+	}
+	return strconv.Itoa(int(d))
+}
+
+// Set parses a ReplyDepth from a flag value
+func (d *ReplyDepth) Set(s string) error {
+	depth, err := ParseReplyDepth(s)
+	if err != nil {
+		return err
+	}
+	*d = depth
+	return nil
+}
+
+// IsBoolFlag allows a ReplyDepth flag to be passed without a value
+func (d *ReplyDepth) IsBoolFlag() bool {
+	return true
+}
+
+// Reply represents a reply to a Thread's tweet or to another Reply, from any user
+type Reply struct {
+	Tweet   *twitter.Tweet `json:"tweet"`
+	Replies []*Reply       `json:"replies,omitempty"`
+}
+
+// LoadReplies searches a Thread's conversation for replies to its tweets, keeping replies up to depth
+// levels below a thread tweet. Replies whose parent tweet was not found, such as replies to deleted
+// tweets, are dropped.
+func (th *Thread) LoadReplies(searcher twitter.ConversationSearcher, depth ReplyDepth) error {
+	if depth == RepliesNone || th.Len() == 0 {
+		return nil
+	}
+
+	tweets, err := searcher.SearchConversation(th.Tweets[0].ConversationID)
+	if err != nil {
+		return err
+	}
+
+	th.Replies = buildReplyTree(th.authorTweets(), tweets, depth)
+	return nil
+}
+
+// buildReplyTree arranges tweets by the tweet they reply to, returning the replies to threadTweets in
+// the order of the tweets they reply to and then chronologically
+func buildReplyTree(threadTweets []*twitter.Tweet, tweets []*twitter.Tweet, depth ReplyDepth) []*Reply {
+	inThread := map[string]struct{}{}
+	for _, tweet := range threadTweets {
+		inThread[tweet.ID] = struct{}{}
+	}
+
+	children := map[string][]*twitter.Tweet{}
+	for _, tweet := range tweets {
+		if _, ok := inThread[tweet.ID]; ok || len(tweet.RepliedToIDs) == 0 {
+			continue
+		}
+		id := parentID(tweet)
+		children[id] = append(children[id], tweet)
+	}
+	for _, replies := range children {
+		sort.SliceStable(replies, func(i, j int) bool {
+			return twitter.LessID(replies[i].ID, replies[j].ID)
+		})
+	}
+
+	var build func(parentID string, level int) []*Reply
+	build = func(parentID string, level int) []*Reply {
+		replies := []*Reply{}
+		if depth != RepliesAll && level > int(depth) {
+			return replies
+		}
+		for _, tweet := range children[parentID] {
+			replies = append(replies, &Reply{
+				Tweet:   tweet,
+				Replies: build(tweet.ID, level+1),
+			})
+		}
+		return replies
+	}
+
+	replies := []*Reply{}
+	for _, tweet := range threadTweets {
+		replies = append(replies, build(tweet.ID, 1)...)
+	}
+	return replies
+}
+
+// Len returns the number of replies below a Reply, including itself
+func (r *Reply) Len() int {
+	n := 1
+	for _, reply := range r.Replies {
+		n += reply.Len()
+	}
+	return n
+}
@@ -2,27 +2,117 @@ package thread
 
 import (
 	"fmt"
+	"math"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
 )
 
 // TemplateThread represents a top level thread for a template
 type TemplateThread struct {
 	Name   string          // Name of thread
 	Header string          // Thread header information
+	Author *TemplateAuthor // Profile of thread's author, nil if not saved
 	Tweets []TemplateTweet // Thread's tweets
 }
 
+// TemplateAuthor represents the profile of a thread's author for a template
+type TemplateAuthor struct {
+	Name        string // Author's name
+	Handle      string // Author's handle
+	URL         string // Author's profile URL
+	Description string // Author's profile description
+	Location    string // Author's self-reported location
+	JoinedAt    string // Month and year in which the author's account was created, empty if unknown
+	Verified    bool   // Author's account is verified
+	Protected   bool   // Author's tweets are protected
+	HasMetrics  bool   // Author's follower, following, and tweet counts are known
+	Followers   int    // Number of accounts following the author
+	Following   int    // Number of accounts the author follows
+	TweetCount  int    // Number of tweets posted by the author
+	Avatar      string // Path to the author's profile image on the local filesystem, empty if not saved
+}
+
 // TemplateTweet represents a tweet for a template
 type TemplateTweet struct {
-	Text        string               // Tweet's text contents
-	Attachments []TemplateAttachment // Tweet's media attachments
+	Text         string               // Tweet's text contents with expanded links
+	HTML         string               // Tweet's text contents as HTML with links to URLs, mentions, hashtags, and cashtags
+	URL          string               // Tweet's URL
+	AuthorName   string               // Tweet author's name
+	AuthorHandle string               // Tweet author's handle
+	OtherAuthor  bool                 // Tweet was posted by an author other than that of the thread's first tweet
+	CreatedAt    string               // Time at which the tweet was posted, formatted for display
+	Metrics      *TemplateMetrics     // Tweet's engagement counts when it was saved, nil if unknown
+	Attachments  []TemplateAttachment // Tweet's media attachments
+	Poll         *TemplatePoll        // Tweet's poll, nil if it does not include a poll
+	Quoted       *TemplateQuote       // Tweet quoted by the tweet, nil if it does not quote a tweet
+	Replies      []TemplateReply      // Saved replies to the tweet from any user
+	Branches     []TemplateBranch     // Side branches of the thread's tweets that the tweet replies to
+}
+
+// TemplateBranch represents a side branch of a thread author's tweets for a template
+type TemplateBranch struct {
+	Tweets []TemplateTweet // Branch's tweets from first to last
+}
+
+// TemplateReply represents a reply to a thread's tweet or to another reply for a template
+type TemplateReply struct {
+	AuthorName   string          // Reply author's name
+	AuthorHandle string          // Reply author's handle
+	URL          string          // Reply's URL
+	CreatedAt    string          // Time at which the reply was posted, formatted for display
+	Text         string          // Reply's text contents with expanded links
+	HTML         string          // Reply's text contents as HTML with links
+	Replies      []TemplateReply // Replies to the reply
+}
+
+// TemplateMetrics represents a tweet's engagement counts for a template
+type TemplateMetrics struct {
+	Replies  int // Number of replies to the tweet
+	Retweets int // Number of retweets of the tweet
+	Quotes   int // Number of tweets quoting the tweet
+	Likes    int // Number of likes of the tweet
+}
+
+// TemplatePoll represents a tweet's poll for a template
+type TemplatePoll struct {
+	Options    []TemplatePollOption // Poll's choices in order
+	TotalVotes int                  // Number of votes cast for all choices
+	EndTime    string               // Time at which voting ends or ended, formatted for display
+	Closed     bool                 // Poll no longer accepts votes
+}
+
+// TemplatePollOption represents a poll's choice for a template
+type TemplatePollOption struct {
+	Label   string // Choice's text
+	Votes   int    // Number of votes cast for the choice
+	Percent int    // Percentage of all votes cast for the choice, rounded to the nearest integer
+}
+
+// TemplateQuote represents a quoted tweet for a template
+type TemplateQuote struct {
+	AuthorName   string               // Quoted tweet author's name
+	AuthorHandle string               // Quoted tweet author's handle
+	URL          string               // Quoted tweet's URL
+	Text         string               // Quoted tweet's text contents with expanded links
+	HTML         string               // Quoted tweet's text contents as HTML with links
+	Attachments  []TemplateAttachment // Quoted tweet's media attachments
 }
 
 // TemplateAttachment represents a tweet's media attachment for a template
 type TemplateAttachment struct {
-	Path string // Path to the attachment file on the local filesystem
-	Ext  string // Attachment's extension
+	Path      string // Path to the attachment file on the local filesystem
+	Ext       string // Attachment's extension
+	MIMEType  string // Attachment's MIME type, detected from its contents or extension
+	AltText   string // Attachment's description for accessibility
+	Width     int    // Attachment's width in pixels, 0 if unknown
+	Height    int    // Attachment's height in pixels, 0 if unknown
+	GIF       bool   // Attachment is an animated GIF saved as a video
+	Poster    string // Path to a video's poster image on the local filesystem, empty if not saved
+	Thumbnail string // Path to a downscaled copy of an image on the local filesystem, empty if not needed
 }
 
 // NewTemplateThread constructs a TemplateThread from a thread
@@ -30,56 +120,239 @@ func NewTemplateThread(th *Thread) TemplateThread {
 	attachmentDir := NewDirectory(th.Dir.Join(dirNameAttachments), "")
 	threadLen := th.Len()
 
+	// Group the top level replies by the thread tweet they reply to
+	replies := map[string][]*Reply{}
+	for _, reply := range th.Replies {
+		if len(reply.Tweet.RepliedToIDs) == 0 {
+			continue
+		}
+		id := parentID(reply.Tweet)
+		replies[id] = append(replies[id], reply)
+	}
+
+	threadAuthorID := ""
+	if threadLen > 0 {
+		threadAuthorID = th.Tweets[0].AuthorID
+	}
+
+	// Group the branches by the tweet that joins them
+	branches := map[string][]*Branch{}
+	for _, branch := range th.Branches {
+		branches[branch.JoinID] = append(branches[branch.JoinID], branch)
+	}
+
 	tweets := []TemplateTweet{}
 	for i, tweet := range th.Tweets {
-		attachments := []TemplateAttachment{}
-		for _, attachment := range tweet.Attachments {
-			attachmentFileName := attachment.Name(tweet.ID)
-
-			// Skip attachment if not downloaded
-			if _, exists := attachmentDir.SubDir(attachmentFileName); !exists {
-				continue
-			}
-
-			attachments = append(attachments, TemplateAttachment{
-				Path: attachmentFileName,
-				Ext:  filepath.Ext(attachmentFileName),
-			})
-		}
-		tweets = append(tweets, TemplateTweet{
-			Text:        fmt.Sprintf("[%d/%d] %s", i+1, threadLen, tweet.Text),
-			Attachments: attachments,
-		})
+		templateTweet := newTemplateTweet(attachmentDir, threadAuthorID, tweet, replies, branches)
+		templateTweet.Text = fmt.Sprintf("[%d/%d] %s", i+1, threadLen, templateTweet.Text)
+		templateTweet.HTML = fmt.Sprintf("[%d/%d] %s", i+1, threadLen, templateTweet.HTML)
+		tweets = append(tweets, templateTweet)
 	}
 
 	return TemplateThread{
 		Name:   th.Name,
 		Header: th.Metadata(),
+		Author: newTemplateAuthor(attachmentDir, th.Author),
 		Tweets: tweets,
 	}
 }
 
-// imageExtensions is a lookup map for identifying image files by extension
-var imageExtensions = map[string]struct{}{
-	".jpg": {},
-	".png": {},
+// newTemplateTweet constructs a TemplateTweet from a tweet of a thread whose first tweet was posted by
+// threadAuthorID, along with the replies to it and the branches it joins, which are grouped by the ID of
+// the tweet they belong to
+func newTemplateTweet(
+	attachmentDir *Directory,
+	threadAuthorID string,
+	tweet *twitter.Tweet,
+	replies map[string][]*Reply,
+	branches map[string][]*Branch,
+) TemplateTweet {
+	templateTweet := TemplateTweet{
+		Text:         tweet.ExpandedText(),
+		HTML:         tweet.HTML(),
+		URL:          tweet.URL,
+		AuthorName:   tweet.AuthorName,
+		AuthorHandle: tweet.AuthorHandle,
+		OtherAuthor:  tweet.AuthorID != threadAuthorID,
+		CreatedAt:    formatTime(tweet.CreatedAt),
+		Metrics:      newTemplateMetrics(tweet.PublicMetrics),
+		Attachments:  newTemplateAttachments(attachmentDir, tweet),
+		Poll:         newTemplatePoll(tweet.Poll),
+		Replies:      newTemplateReplies(replies[tweet.ID]),
+		Branches:     []TemplateBranch{},
+	}
+	if quoted := tweet.QuotedTweet; quoted != nil {
+		templateTweet.Quoted = &TemplateQuote{
+			AuthorName:   quoted.AuthorName,
+			AuthorHandle: quoted.AuthorHandle,
+			URL:          quoted.URL,
+			Text:         quoted.ExpandedText(),
+			HTML:         quoted.HTML(),
+			Attachments:  newTemplateAttachments(attachmentDir, quoted),
+		}
+	}
+	for _, branch := range branches[tweet.ID] {
+		templateBranch := TemplateBranch{Tweets: []TemplateTweet{}}
+		for _, branchTweet := range branch.Tweets {
+			templateBranch.Tweets = append(templateBranch.Tweets, newTemplateTweet(attachmentDir, threadAuthorID, branchTweet, replies, branches))
+		}
+		templateTweet.Branches = append(templateTweet.Branches, templateBranch)
+	}
+	return templateTweet
+}
+
+// newTemplateAuthor constructs a TemplateAuthor from a thread author's profile, returning nil for a nil
+// profile
+func newTemplateAuthor(attachmentDir *Directory, author *twitter.User) *TemplateAuthor {
+	if author == nil {
+		return nil
+	}
+
+	templateAuthor := &TemplateAuthor{
+		Name:        author.Name,
+		Handle:      author.Handle,
+		URL:         author.URL(),
+		Description: author.Description,
+		Location:    author.Location,
+		Verified:    author.Verified,
+		Protected:   author.Protected,
+	}
+	if metrics := author.PublicMetrics; metrics != nil {
+		templateAuthor.HasMetrics = true
+		templateAuthor.Followers = metrics.Followers
+		templateAuthor.Following = metrics.Following
+		templateAuthor.TweetCount = metrics.TweetCount
+	}
+	if t, err := time.Parse(time.RFC3339, author.CreatedAt); err == nil {
+		templateAuthor.JoinedAt = t.Format("January 2006")
+	}
+	if author.Avatar != "" {
+		if _, exists := attachmentDir.SubDir(author.Avatar); exists {
+			templateAuthor.Avatar = author.Avatar
+		}
+	}
+	return templateAuthor
+}
+
+// newTemplateAttachments constructs TemplateAttachments for a tweet's downloaded attachments
+func newTemplateAttachments(attachmentDir *Directory, tweet *twitter.Tweet) []TemplateAttachment {
+	attachments := []TemplateAttachment{}
+	for _, attachment := range tweet.Attachments {
+		attachmentFileName := attachment.Name(tweet.ID)
+
+		// Skip attachment if not downloaded
+		if _, exists := attachmentDir.SubDir(attachmentFileName); !exists {
+			continue
+		}
+
+		ext := filepath.Ext(attachmentFileName)
+		// Attachments saved by older versions have no recorded MIME type
+		mimeType := attachment.MIMEType
+		if mimeType == "" {
+			mimeType = twitter.MIMETypeByExtension(ext)
+		}
+
+		templateAttachment := TemplateAttachment{
+			Path:     attachmentFileName,
+			Ext:      ext,
+			MIMEType: mimeType,
+			AltText:  attachment.AltText,
+			Width:    attachment.Width,
+			Height:   attachment.Height,
+			GIF:      attachment.IsGIF(),
+		}
+		if _, exists := attachmentDir.SubDir(attachment.PosterName(tweet.ID)); exists {
+			templateAttachment.Poster = attachment.PosterName(tweet.ID)
+		}
+		if _, exists := attachmentDir.SubDir(attachment.ThumbnailName(tweet.ID)); exists {
+			templateAttachment.Thumbnail = attachment.ThumbnailName(tweet.ID)
+		}
+
+		attachments = append(attachments, templateAttachment)
+	}
+	return attachments
+}
+
+// newTemplateReplies constructs TemplateReplies from replies and the replies below them
+func newTemplateReplies(replies []*Reply) []TemplateReply {
+	templateReplies := []TemplateReply{}
+	for _, reply := range replies {
+		templateReplies = append(templateReplies, TemplateReply{
+			AuthorName:   reply.Tweet.AuthorName,
+			AuthorHandle: reply.Tweet.AuthorHandle,
+			URL:          reply.Tweet.URL,
+			CreatedAt:    formatTime(reply.Tweet.CreatedAt),
+			Text:         reply.Tweet.ExpandedText(),
+			HTML:         reply.Tweet.HTML(),
+			Replies:      newTemplateReplies(reply.Replies),
+		})
+	}
+	return templateReplies
 }
 
-// videoExtensions is a lookup map for identifying video files by extension
-var videoExtensions = map[string]struct{}{
-	".mp4": {},
+// newTemplateMetrics constructs TemplateMetrics from a tweet's public metrics, returning nil for nil metrics
+func newTemplateMetrics(metrics *twitter.TweetMetrics) *TemplateMetrics {
+	if metrics == nil {
+		return nil
+	}
+	return &TemplateMetrics{
+		Replies:  metrics.Replies,
+		Retweets: metrics.Retweets,
+		Quotes:   metrics.Quotes,
+		Likes:    metrics.Likes,
+	}
 }
 
-// IsImage evaluates if an attachment is an image file
+// newTemplatePoll constructs a TemplatePoll with options ordered by position, returning nil for a nil poll
+func newTemplatePoll(poll *twitter.Poll) *TemplatePoll {
+	if poll == nil {
+		return nil
+	}
+
+	pollOptions := append([]twitter.PollOption{}, poll.Options...)
+	sort.SliceStable(pollOptions, func(i, j int) bool {
+		return pollOptions[i].Position < pollOptions[j].Position
+	})
+
+	total := poll.TotalVotes()
+	options := []TemplatePollOption{}
+	for _, option := range pollOptions {
+		percent := 0
+		if total > 0 {
+			percent = int(math.Round(100 * float64(option.Votes) / float64(total)))
+		}
+		options = append(options, TemplatePollOption{
+			Label:   option.Label,
+			Votes:   option.Votes,
+			Percent: percent,
+		})
+	}
+
+	return &TemplatePoll{
+		Options:    options,
+		TotalVotes: total,
+		EndTime:    formatTime(poll.EndTime),
+		Closed:     poll.IsClosed(),
+	}
+}
+
+// IsImage evaluates if an attachment is an image file by its MIME type
 func (a TemplateAttachment) IsImage() bool {
-	_, valid := imageExtensions[a.Ext]
-	return valid
+	return strings.HasPrefix(a.MIMEType, "image/")
 }
 
-// IsVideo evaluates if an attachment is a video file
+// IsVideo evaluates if an attachment is a video file by its MIME type, including animated GIFs
 func (a TemplateAttachment) IsVideo() bool {
-	_, valid := videoExtensions[a.Ext]
-	return valid
+	return strings.HasPrefix(a.MIMEType, "video/")
+}
+
+// ScaledHeight returns the attachment's height when displayed at the specified width, preserving its
+// aspect ratio, or 0 if its dimensions are unknown
+func (a TemplateAttachment) ScaledHeight(width int) int {
+	if a.Width <= 0 || a.Height <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(width) * float64(a.Height) / float64(a.Width)))
 }
 
 func loadTemplate(threadDir *Directory, templateFileName string, cssFileName string) (string, error) {
@@ -104,19 +377,101 @@ const defaultTemplate = `
 <link rel="stylesheet" type="text/css" href="%s" media="screen" />
 </head>
 <h1>{{.Name}}</h1>
+{{with .Author}}
+	<div class="author">
+		{{with .Avatar}}<img class="avatar" width="64" height="64" src=attachments/{{.}} alt="">{{end}}
+		<p><b>{{html .Name}}</b>{{if .Verified}} &#10004;{{end}}{{if .Protected}} &#128274;{{end}} <a href="{{.URL}}">@{{.Handle}}</a></p>
+		{{with .Description}}<p>{{html .}}</p>{{end}}
+		{{if or .Location .JoinedAt}}<p>{{html .Location}}{{if and .Location .JoinedAt}} &middot; {{end}}{{with .JoinedAt}}Joined {{.}}{{end}}</p>{{end}}
+		{{if .HasMetrics}}<p>{{.Following}} Following &middot; {{.Followers}} Followers &middot; {{.TweetCount}} Tweets</p>{{end}}
+	</div>
+{{end}}
 <div class="text"><pre>{{.Header}}</pre></div>
 {{range .Tweets}}
-	<h3>{{.Text}}</h3>
+	{{template "tweet" .}}
+{{end}}
+{{define "tweet"}}
+	<h3>{{.HTML}}</h3>
+	{{if or .OtherAuthor .CreatedAt .Metrics}}
+		<p class="tweet-info">{{if .OtherAuthor}}<b>{{html .AuthorName}}</b> @{{.AuthorHandle}} &middot; {{end}}<a href="{{.URL}}">{{.CreatedAt}}</a>{{with .Metrics}} &middot; {{.Replies}} replies &middot; {{.Retweets}} retweets &middot; {{.Quotes}} quotes &middot; {{.Likes}} likes{{end}}</p>
+	{{end}}
 	</br></br>
 	{{range .Attachments}}
 		{{if .IsImage}}
-			<img width="320" height="auto" src=attachments/{{.Path}}>
+			<a href=attachments/{{.Path}}><img width="320" height="{{with .ScaledHeight 320}}{{.}}{{else}}auto{{end}}" loading="lazy" src=attachments/{{with .Thumbnail}}{{.}}{{else}}{{.Path}}{{end}} alt="{{html .AltText}}"></a>
 			</br></br>
 		{{end}}
 		{{if .IsVideo}}
-			<video width="320" height="auto" controls autoplay loop muted><source src=attachments/{{.Path}} type="video/mp4"></video>
+			{{if .GIF}}
+				<video width="320" height="{{with .ScaledHeight 320}}{{.}}{{else}}auto{{end}}" autoplay loop muted playsinline {{with .Poster}}poster=attachments/{{.}}{{end}} title="{{html .AltText}}"><source src=attachments/{{.Path}} type="{{.MIMEType}}"></video>
+			{{else}}
+				<video width="320" height="{{with .ScaledHeight 320}}{{.}}{{else}}auto{{end}}" controls preload="{{if .Poster}}none{{else}}metadata{{end}}" {{with .Poster}}poster=attachments/{{.}}{{end}} title="{{html .AltText}}"><source src=attachments/{{.Path}} type="{{.MIMEType}}"></video>
+			{{end}}
 			</br></br>
 		{{end}}
 	{{end}}
+	{{with .Poll}}
+		<div class="poll">
+			{{range .Options}}
+				<div class="poll-option" style="position: relative; width: 320px; margin: 4px 0; border: 1px solid #cfd9de;">
+					<div style="width: {{.Percent}}%%; background-color: #cfd9de;">&nbsp;</div>
+					<span style="position: absolute; top: 0; left: 4px;">{{html .Label}} {{.Percent}}%% ({{.Votes}})</span>
+				</div>
+			{{end}}
+			<p>{{.TotalVotes}} votes &middot; {{if .Closed}}Final results{{else}}Voting ends {{.EndTime}}{{end}}</p>
+		</div>
+		</br>
+	{{end}}
+	{{with .Quoted}}
+		<blockquote class="quoted-tweet">
+			<p><b>{{html .AuthorName}}</b> <a href="{{.URL}}">@{{.AuthorHandle}}</a></p>
+			<p>{{.HTML}}</p>
+			{{range .Attachments}}
+				{{if .IsImage}}
+					<a href=attachments/{{.Path}}><img width="240" height="{{with .ScaledHeight 240}}{{.}}{{else}}auto{{end}}" loading="lazy" src=attachments/{{with .Thumbnail}}{{.}}{{else}}{{.Path}}{{end}} alt="{{html .AltText}}"></a>
+				{{end}}
+				{{if .IsVideo}}
+					{{if .GIF}}
+						<video width="240" height="{{with .ScaledHeight 240}}{{.}}{{else}}auto{{end}}" autoplay loop muted playsinline {{with .Poster}}poster=attachments/{{.}}{{end}} title="{{html .AltText}}"><source src=attachments/{{.Path}} type="{{.MIMEType}}"></video>
+					{{else}}
+						<video width="240" height="{{with .ScaledHeight 240}}{{.}}{{else}}auto{{end}}" controls preload="{{if .Poster}}none{{else}}metadata{{end}}" {{with .Poster}}poster=attachments/{{.}}{{end}} title="{{html .AltText}}"><source src=attachments/{{.Path}} type="{{.MIMEType}}"></video>
+					{{end}}
+				{{end}}
+			{{end}}
+		</blockquote>
+		</br>
+	{{end}}
+	{{with .Replies}}
+		<details class="replies">
+			<summary>{{len .}} {{if eq (len .) 1}}reply{{else}}replies{{end}}</summary>
+			{{template "replies" .}}
+		</details>
+		</br>
+	{{end}}
+	{{range .Branches}}
+		<details class="branch">
+			<summary>Replies to a side branch of {{len .Tweets}} {{if eq (len .Tweets) 1}}tweet{{else}}tweets{{end}}</summary>
+			<div style="margin-left: 16px; border-left: 2px solid #1d9bf0; padding-left: 8px;">
+				{{range .Tweets}}
+					{{template "tweet" .}}
+				{{end}}
+			</div>
+		</details>
+		</br>
+	{{end}}
+{{end}}
+{{define "replies"}}
+	{{range .}}
+		<div class="reply" style="margin-left: 16px; border-left: 2px solid #cfd9de; padding-left: 8px;">
+			<p><b>{{html .AuthorName}}</b> <a href="{{.URL}}">@{{.AuthorHandle}}</a> &middot; {{.CreatedAt}}</p>
+			<p>{{.HTML}}</p>
+			{{with .Replies}}
+				<details>
+					<summary>{{len .}} {{if eq (len .) 1}}reply{{else}}replies{{end}}</summary>
+					{{template "replies" .}}
+				</details>
+			{{end}}
+		</div>
+	{{end}}
 {{end}}
 `
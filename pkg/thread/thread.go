@@ -3,20 +3,25 @@ package thread
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dkaslovsky/thread-safe/pkg/twitter"
 )
 
-const (
-	// maxThreadLen is the maximum number of tweets to be fetched for constructing a thread
-	maxThreadLen = 100
-)
+// timeFormatDisplay is the format of times displayed in a Thread's header and HTML file
+const timeFormatDisplay = "Jan 2, 2006 15:04 MST"
 
-// Thread represents a Twitter thread
+// Thread represents a Twitter thread as its main chain of tweets along with any side branches of the
+// author's tweets
 type Thread struct {
-	Dir    *Directory       `json:"-"`
-	Name   string           `json:"name"`
-	Tweets []*twitter.Tweet `json:"tweets"`
+	Dir      *Directory       `json:"-"`
+	Name     string           `json:"name"`
+	SavedAt  string           `json:"saved_at,omitempty"`
+	Version  string           `json:"version,omitempty"`
+	Author   *twitter.User    `json:"author,omitempty"`
+	Tweets   []*twitter.Tweet `json:"tweets"`
+	Branches []*Branch        `json:"branches,omitempty"`
+	Replies  []*Reply         `json:"replies,omitempty"`
 }
 
 // New constructs a Thread that is ready to load tweets from the Twitter API
@@ -27,9 +32,10 @@ func New(topLevelDir string, name string) *Thread {
 	}
 }
 
-// Load queries the Twitter API to load tweets into a Thread
-func (th *Thread) Load(client twitter.Client, lastTweetID string) error {
-	tweets, err := walkTweets(client, lastTweetID, maxThreadLen)
+// Load queries the Twitter API to load tweets into a Thread, following the chain of tweets from
+// lastTweetID until the policy ends it
+func (th *Thread) Load(client twitter.Client, lastTweetID string, policy StopPolicy) error {
+	tweets, branches, err := walkTweets(client, lastTweetID, policy)
 	if err != nil {
 		return err
 	}
@@ -38,14 +44,64 @@ func (th *Thread) Load(client twitter.Client, lastTweetID string) error {
 	reverseSlice(tweets)
 
 	th.Tweets = tweets
+	th.Branches = branches
+	th.Author = author(tweets)
 	return nil
 }
 
-// Len returns the number of tweets contained in a Thread
+// Stamp records the time at which a Thread was saved and the version of thread-safe that saved it, which
+// is empty for builds from source
+func (th *Thread) Stamp(version string, savedAt time.Time) {
+	th.SavedAt = savedAt.UTC().Format(time.RFC3339)
+	th.Version = version
+}
+
+// Len returns the number of tweets contained in a Thread's main chain
 func (th *Thread) Len() int {
 	return len(th.Tweets)
 }
 
+// authorTweets returns the tweets of a Thread's main chain followed by the tweets of its branches
+func (th *Thread) authorTweets() []*twitter.Tweet {
+	tweets := append([]*twitter.Tweet{}, th.Tweets...)
+	for _, branch := range th.Branches {
+		tweets = append(tweets, branch.Tweets...)
+	}
+	return tweets
+}
+
+// allTweets returns a Thread's tweets, including those of its branches, followed by the tweets they quote
+func (th *Thread) allTweets() []*twitter.Tweet {
+	tweets := th.authorTweets()
+	for _, tweet := range th.authorTweets() {
+		if tweet.QuotedTweet != nil {
+			tweets = append(tweets, tweet.QuotedTweet)
+		}
+	}
+	return tweets
+}
+
+// author returns the profile of the author of the first tweet with a known author, or nil if no tweet
+// has a known author
+func author(tweets []*twitter.Tweet) *twitter.User {
+	for _, tweet := range tweets {
+		if tweet.Author != nil {
+			return tweet.Author
+		}
+	}
+	return nil
+}
+
+// SetVideoQuality selects the variant of each video attachment to be downloaded, including those of
+// quoted tweets
+func (th *Thread) SetVideoQuality(quality twitter.VideoQuality) {
+	for _, tweet := range th.allTweets() {
+		for i, attachment := range tweet.Attachments {
+			tweet.Attachments[i] = attachment.WithVideoQuality(quality)
+		}
+	}
+}
+
 // Metadata returns a string with thread metadata
 func (th *Thread) Metadata() string {
 	if th.Len() == 0 {
@@ -58,49 +114,37 @@ func (th *Thread) Metadata() string {
 		fmt.Sprintf("Author Handle: \t\t%s", first.AuthorHandle),
 		fmt.Sprintf("Conversation ID: \t%s", first.ConversationID),
 	}
+	if createdAt, err := first.CreatedTime(); err == nil {
+		headerStrs = append(headerStrs, fmt.Sprintf("Posted At: \t\t%s", createdAt.UTC().Format(timeFormatDisplay)))
+	}
+	// Threads saved by older versions do not record when or by which version they were saved
+	if th.SavedAt != "" {
+		headerStrs = append(headerStrs, fmt.Sprintf("Saved At: \t\t%s", formatTime(th.SavedAt)))
+	}
+	if th.Version != "" {
+		headerStrs = append(headerStrs, fmt.Sprintf("Saved By Version: \t%s", th.Version))
+	}
+	if len(th.Branches) > 0 {
+		headerStrs = append(headerStrs, fmt.Sprintf("Side Branches: \t\t%d", len(th.Branches)))
+	}
+	if len(th.Replies) > 0 {
+		replyCount := 0
+		for _, reply := range th.Replies {
+			replyCount += reply.Len()
+		}
+		headerStrs = append(headerStrs, fmt.Sprintf("Saved Replies: \t\t%d", replyCount))
+	}
 	return strings.Join(headerStrs, "\n")
 }
 
-// walkTweets queries for tweets by following the RepliedToID of the starting tweet and stopping
-// once no more tweets are in the chain or a new conversation ID or author ID is encountered
-func walkTweets(client twitter.Client, id string, limit int) ([]*twitter.Tweet, error) {
-	tweets := []*twitter.Tweet{}
-
-	nextID := id
-	conversationID := ""
-	authorID := ""
-
-	for i := 0; i < limit; i++ {
-		tweet, err := client.LookupTweet(nextID)
-		if err != nil {
-			return nil, err
-		}
-
-		// Save the conversationID and authorID
-		if i == 0 {
-			conversationID = tweet.ConversationID
-			authorID = tweet.AuthorID
-		}
-
-		// A change in conversationID or authorID indicates the end of the current thread
-		if tweet.ConversationID != conversationID || tweet.AuthorID != authorID {
-			return tweets, nil
-		}
-
-		tweets = append(tweets, tweet)
-
-		switch len(tweet.RepliedToIDs) {
-		case 1: // Next ID for lookup
-			nextID = tweet.RepliedToIDs[0]
-		case 0: // Top of thread has been reached
-			return tweets, nil
-		default: // Error on multiple replied_to IDs
-			return nil, fmt.Errorf("cannot follow tweet %s with multiple replied_to IDs", tweet.ID)
-		}
+// formatTime formats a timestamp returned by the Twitter API for display in UTC, returning the timestamp
+// unchanged if it cannot be parsed
+func formatTime(timestamp string) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp
 	}
-
-	// Limit reached
-	return nil, fmt.Errorf("exceeded maximum number of tweets to fetch [%d]", limit)
+	return t.UTC().Format(timeFormatDisplay)
 }
 
 func reverseSlice[T any](s []T) {
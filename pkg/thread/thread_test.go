@@ -0,0 +1,117 @@
+package thread
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+const (
+	fixturesNathanMacKinnon = "../twitter/twittertest/testdata/Nathan_MacKinnon_2018"
+	fixturesFeatures        = "../twitter/twittertest/testdata/features"
+)
+
+func TestLoad(t *testing.T) {
+	tests := map[string]struct {
+		fixtures       string
+		lastTweetID    string
+		policy         StopPolicy
+		expectedIDs    []string
+		expectedAuthor string
+		expectedErr    bool
+	}{
+		"thread": {
+			fixtures:    fixturesNathanMacKinnon,
+			lastTweetID: "969990907490484225",
+			expectedIDs: []string{
+				"969990878944149504",
+				"969990884300267521",
+				"969990886430949376",
+				"969990889576607744",
+				"969990894664368128",
+				"969990896925028352",
+				"969990901534633985",
+				"969990907490484225",
+			},
+			expectedAuthor: "Avalanche",
+		},
+		"thread ending before its last tweet": {
+			fixtures:    fixturesFeatures,
+			lastTweetID: "1700000000000000003",
+			expectedIDs: []string{
+				"1700000000000000001",
+				"1700000000000000002",
+				"1700000000000000003",
+			},
+			expectedAuthor: "thread_safe_dev",
+		},
+		"single tweet": {
+			fixtures:       fixturesFeatures,
+			lastTweetID:    "1700000000000000001",
+			expectedIDs:    []string{"1700000000000000001"},
+			expectedAuthor: "thread_safe_dev",
+		},
+		"stop at tweet": {
+			fixtures:    fixturesFeatures,
+			lastTweetID: "1700000000000000003",
+			policy:      AllOf(DefaultStopPolicy(), StopAtTweet("1700000000000000002")),
+			expectedIDs: []string{
+				"1700000000000000002",
+				"1700000000000000003",
+			},
+			expectedAuthor: "thread_safe_dev",
+		},
+		"stop at tweet after last tweet": {
+			fixtures:    fixturesFeatures,
+			lastTweetID: "1700000000000000003",
+			policy:      AllOf(DefaultStopPolicy(), StopAtTweet("1700000000000000009")),
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := test.policy
+			if policy == nil {
+				policy = DefaultStopPolicy()
+			}
+
+			th := New(t.TempDir(), "thread")
+			err := th.Load(twittertest.NewClient(t, test.fixtures), test.lastTweetID, policy)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got tweets %v", tweetIDs(th.Tweets))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ids := tweetIDs(th.Tweets); !reflect.DeepEqual(ids, test.expectedIDs) {
+				t.Errorf("expected tweets %v, got %v", test.expectedIDs, ids)
+			}
+			if th.Author == nil || th.Author.Handle != test.expectedAuthor {
+				t.Errorf("expected author %s, got %+v", test.expectedAuthor, th.Author)
+			}
+		})
+	}
+}
+
+func TestLoadUnknownTweet(t *testing.T) {
+	th := New(t.TempDir(), "thread")
+	err := th.Load(twittertest.NewClient(t, fixturesFeatures), "1", DefaultStopPolicy())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func tweetIDs(tweets []*twitter.Tweet) []string {
+	ids := []string{}
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+	}
+	return ids
+}
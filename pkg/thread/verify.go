@@ -0,0 +1,72 @@
+package thread
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// AttachmentProblem describes a saved attachment file that is missing or does not match the size and
+// digest recorded when it was saved
+type AttachmentProblem struct {
+	TweetID    string
+	Attachment *twitter.Attachment
+	Err        error
+}
+
+// VerifyAttachments checks that the file of each attachment exists and matches its size and digest, if
+// they were recorded, returning the attachments whose files are missing or do not match. Threads saved
+// without attachments have no attachments directory and are not checked.
+func (th *Thread) VerifyAttachments() []AttachmentProblem {
+	attachmentDir := NewDirectory(th.Dir.Join(dirNameAttachments), "")
+
+	problems := []AttachmentProblem{}
+	if !attachmentDir.Exists() {
+		return problems
+	}
+	for _, tweet := range th.allTweets() {
+		for i := range tweet.Attachments {
+			attachment := &tweet.Attachments[i]
+			err := attachment.Verify(attachmentDir.Join(attachment.Name(tweet.ID)))
+			if err != nil {
+				problems = append(problems, AttachmentProblem{
+					TweetID:    tweet.ID,
+					Attachment: attachment,
+					Err:        err,
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// RepairAttachment downloads an attachment found by VerifyAttachments again, at the image size that
+// was previously saved, updating its recorded size and digest and its thumbnail
+func (th *Thread) RepairAttachment(client *http.Client, problem AttachmentProblem) error {
+	attachmentDir := NewDirectory(th.Dir.Join(dirNameAttachments), "")
+	err := attachmentDir.Create()
+	if err != nil {
+		return err
+	}
+
+	attachment := problem.Attachment
+	dErr := attachment.Download(client, attachmentDir.String(), problem.TweetID, attachment.ImageSize)
+	if dErr != nil {
+		return dErr
+	}
+	return generateThumbnail(attachmentDir, problem.TweetID, *attachment)
+}
+
+// HTMLIsStale evaluates if a Thread's HTML file is missing or older than its JSON file
+func (th *Thread) HTMLIsStale() bool {
+	htmlInfo, err := os.Stat(th.Dir.Join(fileNameHTML))
+	if err != nil {
+		return true
+	}
+	jsonInfo, jErr := os.Stat(th.Dir.Join(fileNameJSON))
+	if jErr != nil {
+		return false
+	}
+	return htmlInfo.ModTime().Before(jsonInfo.ModTime())
+}
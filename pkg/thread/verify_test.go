@@ -0,0 +1,87 @@
+package thread
+
+import (
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+func TestVerifyAttachments(t *testing.T) {
+	tests := map[string]struct {
+		// contents is the contents of the attachment file, which is not saved if nil
+		contents []byte
+		// recorded is whether the size and digest of the file "contents" are recorded
+		recorded bool
+		// noAttachmentDir is whether the thread was saved without an attachments directory
+		noAttachmentDir bool
+		expectedProblem bool
+	}{
+		"recorded file matches": {
+			contents:        []byte("contents"),
+			recorded:        true,
+			expectedProblem: false,
+		},
+		"recorded file is damaged": {
+			contents:        []byte("damaged!"),
+			recorded:        true,
+			expectedProblem: true,
+		},
+		"recorded file is truncated": {
+			contents:        []byte("content"),
+			recorded:        true,
+			expectedProblem: true,
+		},
+		"recorded file is missing": {
+			recorded:        true,
+			expectedProblem: true,
+		},
+		"unrecorded file exists": {
+			contents:        []byte("anything"),
+			recorded:        false,
+			expectedProblem: false,
+		},
+		"unrecorded file is missing": {
+			recorded:        false,
+			expectedProblem: true,
+		},
+		"saved without attachments": {
+			recorded:        false,
+			noAttachmentDir: true,
+			expectedProblem: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			th := New(t.TempDir(), "thread")
+			attachment := twitter.Attachment{
+				MediaKey: "3_1",
+				Type:     twitter.MediaTypePhoto,
+				URL:      "https://pbs.twimg.com/media/image.jpg",
+			}
+			if test.recorded {
+				// The size and digest of "contents"
+				attachment.Size = 8
+				attachment.SHA256 = "d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8"
+			}
+			th.Tweets = []*twitter.Tweet{{ID: "1", Attachments: []twitter.Attachment{attachment}}}
+
+			if !test.noAttachmentDir {
+				if err := NewDirectory(th.Dir.Join(dirNameAttachments), "").Create(); err != nil {
+					t.Fatalf("failed to create attachments directory: %v", err)
+				}
+			}
+			if test.contents != nil {
+				writeTestFile(t, th.Dir.Join(dirNameAttachments, attachment.Name("1")), test.contents)
+			}
+
+			problems := th.VerifyAttachments()
+			if (len(problems) > 0) != test.expectedProblem {
+				t.Fatalf("expected problem %t, got %+v", test.expectedProblem, problems)
+			}
+			if test.expectedProblem && (problems[0].TweetID != "1" || problems[0].Attachment.MediaKey != "3_1") {
+				t.Errorf("expected problem with attachment 3_1 of tweet 1, got %+v", problems[0])
+			}
+		})
+	}
+}
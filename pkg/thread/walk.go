@@ -0,0 +1,150 @@
+package thread
+
+import (
+	"fmt"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// Branch is a chain of a thread's tweets outside of its main chain, which is found when one of the
+// thread's tweets replies to more than one tweet
+type Branch struct {
+	// ForkID is the ID of the already saved tweet that the first tweet of the branch replies to, which
+	// is empty if the branch starts its own chain
+	ForkID string `json:"fork_id,omitempty"`
+	// JoinID is the ID of the already saved tweet that replies to the last tweet of the branch
+	JoinID string           `json:"join_id"`
+	Tweets []*twitter.Tweet `json:"tweets"`
+}
+
+// parentID returns the ID of the tweet that a tweet continues, which is the earliest of the tweets it
+// replies to since that tweet is closest to the start of the conversation, or an empty string if the
+// tweet is not a reply
+func parentID(tweet *twitter.Tweet) string {
+	id := ""
+	for _, repliedToID := range tweet.RepliedToIDs {
+		if id == "" || twitter.LessID(repliedToID, id) {
+			id = repliedToID
+		}
+	}
+	return id
+}
+
+// walkTweets queries for tweets by following the parent of the starting tweet and stopping once no
+// more tweets are in the chain, a new conversation ID is encountered, or the policy ends the chain,
+// returning the main chain from last to first. Any other tweets replied to along the way are followed in
+// the same manner, until reaching an already fetched tweet, and returned as branches with their tweets
+// from first to last.
+func walkTweets(client twitter.Client, id string, policy StopPolicy) ([]*twitter.Tweet, []*Branch, error) {
+	w := &walker{
+		client:  client,
+		policy:  policy,
+		fetched: map[string]struct{}{},
+	}
+
+	tweets, pending, _, err := w.walk(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The policy can end the chain at the starting tweet, which leaves no thread to save
+	if len(tweets) == 0 {
+		return nil, nil, fmt.Errorf("no tweets found ending with tweet %s", id)
+	}
+
+	branches := []*Branch{}
+	for len(pending) > 0 {
+		ref := pending[0]
+		pending = pending[1:]
+
+		branchTweets, branchPending, forkID, bErr := w.walk(ref.id)
+		if bErr != nil {
+			return nil, nil, bErr
+		}
+		pending = append(pending, branchPending...)
+
+		// Tweets ending the chain by the policy or in another conversation are not part of the thread
+		if len(branchTweets) == 0 {
+			continue
+		}
+
+		reverseSlice(branchTweets)
+		branches = append(branches, &Branch{
+			ForkID: forkID,
+			JoinID: ref.joinID,
+			Tweets: branchTweets,
+		})
+	}
+
+	return tweets, branches, nil
+}
+
+// walker follows the chain of a thread's tweets, tracking the tweets already fetched so that each tweet
+// is fetched once
+type walker struct {
+	client  twitter.Client
+	policy  StopPolicy
+	fetched map[string]struct{}
+
+	start *twitter.Tweet
+}
+
+// branchRef is a replied_to ID that was not followed as a tweet's parent
+type branchRef struct {
+	id     string
+	joinID string // ID of the tweet replying to the tweet with ID id
+}
+
+// walk follows parent IDs starting from the tweet with the given ID, returning the thread's tweets
+// from last to first, references to the other tweets they reply to, and the ID of the already fetched
+// tweet at which the walk stopped, if any
+func (w *walker) walk(id string) ([]*twitter.Tweet, []branchRef, string, error) {
+	tweets := []*twitter.Tweet{}
+	refs := []branchRef{}
+
+	nextID := id
+	for {
+		if _, ok := w.fetched[nextID]; ok {
+			return tweets, refs, nextID, nil
+		}
+
+		tweet, err := w.client.LookupTweet(nextID)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		// Save the starting tweet for comparing to the tweets that follow
+		if w.start == nil {
+			w.start = tweet
+		}
+
+		// A change in conversationID indicates the end of the current thread
+		if tweet.ConversationID != w.start.ConversationID {
+			return tweets, refs, "", nil
+		}
+
+		decision, dErr := w.policy.Decide(w.start, tweet, len(w.fetched))
+		if dErr != nil {
+			return nil, nil, "", dErr
+		}
+		if decision == Stop {
+			return tweets, refs, "", nil
+		}
+
+		w.fetched[tweet.ID] = struct{}{}
+		tweets = append(tweets, tweet)
+
+		if decision == Last {
+			return tweets, refs, "", nil
+		}
+
+		nextID = parentID(tweet)
+		if nextID == "" { // Top of thread has been reached
+			return tweets, refs, "", nil
+		}
+		for _, repliedToID := range tweet.RepliedToIDs {
+			if repliedToID != nextID {
+				refs = append(refs, branchRef{id: repliedToID, joinID: tweet.ID})
+			}
+		}
+	}
+}
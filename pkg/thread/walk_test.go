@@ -0,0 +1,188 @@
+package thread
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+func TestWalkTweets(t *testing.T) {
+	tests := map[string]struct {
+		id               string
+		policy           StopPolicy
+		expectedIDs      []string
+		expectedBranches []expectedBranch
+	}{
+		"branch joined by a tweet replying to two tweets": {
+			id:     "1700000000000000007",
+			policy: DefaultStopPolicy(),
+			expectedIDs: []string{
+				"1700000000000000007",
+				"1700000000000000006",
+				"1700000000000000005",
+				"1700000000000000004",
+				"1700000000000000003",
+				"1700000000000000002",
+				"1700000000000000001",
+			},
+			expectedBranches: []expectedBranch{
+				{forkID: "1700000000000000003", joinID: "1700000000000000007", ids: []string{"1700000000000000050"}},
+			},
+		},
+		"branch followed past a second author": {
+			id:     "1700000000000000009",
+			policy: AllowedAuthors("@Thread_Safe_Docs"),
+			expectedIDs: []string{
+				"1700000000000000009",
+				"1700000000000000008",
+				"1700000000000000007",
+				"1700000000000000006",
+				"1700000000000000005",
+				"1700000000000000004",
+				"1700000000000000003",
+				"1700000000000000002",
+				"1700000000000000001",
+			},
+			expectedBranches: []expectedBranch{
+				{forkID: "1700000000000000003", joinID: "1700000000000000007", ids: []string{"1700000000000000050"}},
+			},
+		},
+		"stopped at second author": {
+			id:               "1700000000000000009",
+			policy:           DefaultStopPolicy(),
+			expectedIDs:      []string{"1700000000000000009"},
+			expectedBranches: []expectedBranch{},
+		},
+		"branch tweet as last tweet": {
+			id:     "1700000000000000050",
+			policy: DefaultStopPolicy(),
+			expectedIDs: []string{
+				"1700000000000000050",
+				"1700000000000000003",
+				"1700000000000000002",
+				"1700000000000000001",
+			},
+			expectedBranches: []expectedBranch{},
+		},
+		"branch forking before the first tweet": {
+			id:     "1700000000000000007",
+			policy: AllOf(SingleAuthor(), StopAtTweet("1700000000000000004")),
+			expectedIDs: []string{
+				"1700000000000000007",
+				"1700000000000000006",
+				"1700000000000000005",
+				"1700000000000000004",
+			},
+			expectedBranches: []expectedBranch{
+				{forkID: "", joinID: "1700000000000000007", ids: []string{"1700000000000000050"}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tweets, branches, err := walkTweets(twittertest.NewClient(t, fixturesFeatures), test.id, test.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ids := tweetIDs(tweets); !reflect.DeepEqual(ids, test.expectedIDs) {
+				t.Errorf("expected tweets %v, got %v", test.expectedIDs, ids)
+			}
+			checkBranches(t, branches, test.expectedBranches)
+		})
+	}
+}
+
+func TestWalkTweetsNestedBranches(t *testing.T) {
+	// 1 <- 2 <- 5 is the main chain, 5 also replies to 4, which continues 3 and also replies to 31, which
+	// starts its own chain
+	client := fakeClient{
+		"1":  {ID: "1"},
+		"2":  {ID: "2", RepliedToIDs: []string{"1"}},
+		"3":  {ID: "3", RepliedToIDs: []string{"1"}},
+		"31": {ID: "31"},
+		"4":  {ID: "4", RepliedToIDs: []string{"3", "31"}},
+		"5":  {ID: "5", RepliedToIDs: []string{"4", "2"}},
+	}
+
+	tweets, branches, err := walkTweets(client, "5", AnyAuthor())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedIDs := []string{"5", "2", "1"}
+	if ids := tweetIDs(tweets); !reflect.DeepEqual(ids, expectedIDs) {
+		t.Errorf("expected tweets %v, got %v", expectedIDs, ids)
+	}
+	checkBranches(t, branches, []expectedBranch{
+		{forkID: "1", joinID: "5", ids: []string{"3", "4"}},
+		{forkID: "", joinID: "4", ids: []string{"31"}},
+	})
+}
+
+func TestParentID(t *testing.T) {
+	tests := map[string]struct {
+		repliedToIDs []string
+		expected     string
+	}{
+		"not a reply": {
+			repliedToIDs: nil,
+			expected:     "",
+		},
+		"single reply": {
+			repliedToIDs: []string{"1700000000000000006"},
+			expected:     "1700000000000000006",
+		},
+		"earliest of replies": {
+			repliedToIDs: []string{"1700000000000000006", "1700000000000000050", "999"},
+			expected:     "999",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := parentID(&twitter.Tweet{RepliedToIDs: test.repliedToIDs})
+			if got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+type expectedBranch struct {
+	forkID string
+	joinID string
+	ids    []string
+}
+
+func checkBranches(t *testing.T, branches []*Branch, expected []expectedBranch) {
+	t.Helper()
+	if len(branches) != len(expected) {
+		t.Fatalf("expected %d branches, got %d", len(expected), len(branches))
+	}
+	for i, branch := range branches {
+		if branch.ForkID != expected[i].forkID {
+			t.Errorf("expected branch %d to fork from %q, got %q", i, expected[i].forkID, branch.ForkID)
+		}
+		if branch.JoinID != expected[i].joinID {
+			t.Errorf("expected branch %d to join at %q, got %q", i, expected[i].joinID, branch.JoinID)
+		}
+		if ids := tweetIDs(branch.Tweets); !reflect.DeepEqual(ids, expected[i].ids) {
+			t.Errorf("expected branch %d tweets %v, got %v", i, expected[i].ids, ids)
+		}
+	}
+}
+
+// fakeClient is a twitter.Client looking up tweets of a single conversation by ID
+type fakeClient map[string]*twitter.Tweet
+
+func (c fakeClient) LookupTweet(id string) (*twitter.Tweet, error) {
+	tweet, ok := c[id]
+	if !ok {
+		return nil, fmt.Errorf("tweet %s not found", id)
+	}
+	tweet.ConversationID = "1"
+	return tweet, nil
+}
@@ -0,0 +1,138 @@
+// Package thumbnail writes downscaled JPEG copies of images for displaying in place of full size media
+package thumbnail
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+
+	// Register decoders for the image formats supported by the standard library
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// jpegQuality is the quality with which thumbnails are encoded
+const jpegQuality = 80
+
+// ErrUnsupported is returned for images in formats that cannot be decoded
+var ErrUnsupported = errors.New("unsupported image format")
+
+// Create writes a JPEG copy of the image in src, downscaled to width pixels wide, to dst, returning
+// false without writing dst if the image is no wider than width
+func Create(src string, dst string, width int) (bool, error) {
+	img, err := decode(src)
+	if err != nil {
+		return false, err
+	}
+	if img.Bounds().Dx() <= width {
+		return false, nil
+	}
+	return true, save(scale(img, width), dst)
+}
+
+// Convert writes a JPEG copy of the image in src to dst, downscaled to width pixels wide if it is wider
+func Convert(src string, dst string, width int) error {
+	img, err := decode(src)
+	if err != nil {
+		return err
+	}
+	return save(scale(img, width), dst)
+}
+
+// decode reads an image from a file, using the first frame of an animated image
+func decode(fileName string) (image.Image, error) {
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	img, _, dErr := image.Decode(f)
+	if errors.Is(dErr, image.ErrFormat) {
+		return nil, ErrUnsupported
+	}
+	return img, dErr
+}
+
+// scale downscales an image to width pixels wide, preserving its aspect ratio, by averaging the pixels
+// covered by each pixel of the result
+func scale(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	if width <= 0 || srcWidth <= width {
+		return img
+	}
+	height := (srcHeight*width + srcWidth/2) / srcWidth
+	if height < 1 {
+		height = 1
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcWidth, srcHeight))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := span(y, height, srcHeight)
+		for x := 0; x < width; x++ {
+			x0, x1 := span(x, width, srcWidth)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					for c := range sum {
+						sum[c] += int(src.Pix[offset+c])
+					}
+					offset += 4
+				}
+			}
+
+			n := (x1 - x0) * (y1 - y0)
+			offset := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[offset+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+// span returns the range of source pixels covered by pixel i of n pixels scaled from srcN pixels,
+// which always includes at least one pixel when downscaling
+func span(i int, n int, srcN int) (int, int) {
+	start, end := i*srcN/n, (i+1)*srcN/n
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// save writes an image as a JPEG file, drawing it over a white background since JPEG does not support
+// transparency
+func save(img image.Image, fileName string) error {
+	bounds := img.Bounds()
+	opaque := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(opaque, opaque.Bounds(), img, bounds.Min, draw.Over)
+
+	tmpFileName := fileName + ".tmp"
+	f, err := os.OpenFile(filepath.Clean(tmpFileName), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	eErr := jpeg.Encode(f, opaque, &jpeg.Options{Quality: jpegQuality})
+	if cErr := f.Close(); eErr == nil {
+		eErr = cErr
+	}
+	if eErr != nil {
+		_ = os.Remove(tmpFileName)
+		return eErr
+	}
+	return os.Rename(tmpFileName, fileName)
+}
@@ -0,0 +1,214 @@
+package thumbnail
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	tests := map[string]struct {
+		width           int
+		height          int
+		expectedCreated bool
+		expectedWidth   int
+		expectedHeight  int
+	}{
+		"wider than thumbnail": {
+			width:           1200,
+			height:          675,
+			expectedCreated: true,
+			expectedWidth:   640,
+			expectedHeight:  360,
+		},
+		"tall and narrow": {
+			width:           1280,
+			height:          1,
+			expectedCreated: true,
+			expectedWidth:   640,
+			expectedHeight:  1,
+		},
+		"as wide as thumbnail": {
+			width:           640,
+			height:          480,
+			expectedCreated: false,
+		},
+		"narrower than thumbnail": {
+			width:           320,
+			height:          240,
+			expectedCreated: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := writePNG(t, dir, test.width, test.height)
+			dst := filepath.Join(dir, "thumb.jpg")
+
+			created, err := Create(src, dst, 640)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if created != test.expectedCreated {
+				t.Fatalf("expected created %t, got %t", test.expectedCreated, created)
+			}
+			if !created {
+				if _, sErr := os.Stat(dst); !os.IsNotExist(sErr) {
+					t.Errorf("expected no thumbnail written, got %v", sErr)
+				}
+				return
+			}
+			config := readJPEGConfig(t, dst)
+			if config.Width != test.expectedWidth || config.Height != test.expectedHeight {
+				t.Errorf("expected %dx%d, got %dx%d", test.expectedWidth, test.expectedHeight, config.Width, config.Height)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := map[string]struct {
+		width          int
+		height         int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		"downscaled": {
+			width:          1280,
+			height:         720,
+			expectedWidth:  640,
+			expectedHeight: 360,
+		},
+		"kept at its size": {
+			width:          320,
+			height:         180,
+			expectedWidth:  320,
+			expectedHeight: 180,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := writePNG(t, dir, test.width, test.height)
+			dst := filepath.Join(dir, "poster.jpg")
+
+			if err := Convert(src, dst, 640); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config := readJPEGConfig(t, dst)
+			if config.Width != test.expectedWidth || config.Height != test.expectedHeight {
+				t.Errorf("expected %dx%d, got %dx%d", test.expectedWidth, test.expectedHeight, config.Width, config.Height)
+			}
+			if _, sErr := os.Stat(dst + ".tmp"); !os.IsNotExist(sErr) {
+				t.Errorf("expected temporary file removed, got %v", sErr)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]struct {
+		contents            []byte
+		expectedUnsupported bool
+	}{
+		"unsupported format": {
+			contents:            []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+			expectedUnsupported: true,
+		},
+		"truncated image": {
+			contents:            []byte("\x89PNG\r\n\x1a\n"),
+			expectedUnsupported: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "image")
+			if err := os.WriteFile(src, test.contents, 0o600); err != nil {
+				t.Fatalf("failed to write %s: %v", src, err)
+			}
+			dst := filepath.Join(dir, "thumb.jpg")
+
+			_, err := Create(src, dst, 640)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if errors.Is(err, ErrUnsupported) != test.expectedUnsupported {
+				t.Errorf("expected unsupported %t, got %v", test.expectedUnsupported, err)
+			}
+			if _, sErr := os.Stat(dst); !os.IsNotExist(sErr) {
+				t.Errorf("expected no thumbnail written, got %v", sErr)
+			}
+		})
+	}
+}
+
+func TestScaleAveragesPixels(t *testing.T) {
+	// Alternating black and white columns average to gray when halved
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 2; y++ {
+			c := color.RGBA{A: 255}
+			if x%2 == 1 {
+				c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	scaled := scale(img, 2)
+	if bounds := scaled.Bounds(); bounds.Dx() != 2 || bounds.Dy() != 1 {
+		t.Fatalf("expected 2x1, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	r, g, b, a := scaled.At(0, 0).RGBA()
+	if r>>8 != 128 || g>>8 != 128 || b>>8 != 128 || a>>8 != 255 {
+		t.Errorf("expected gray, got (%d, %d, %d, %d)", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+func writePNG(t *testing.T, dir string, width int, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	fileName := filepath.Join(dir, "image.png")
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", fileName, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if eErr := png.Encode(f, img); eErr != nil {
+		t.Fatalf("failed to encode %s: %v", fileName, eErr)
+	}
+	return fileName
+}
+
+func readJPEGConfig(t *testing.T, fileName string) image.Config {
+	t.Helper()
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", fileName, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	config, dErr := jpeg.DecodeConfig(f)
+	if dErr != nil {
+		t.Fatalf("failed to decode %s: %v", fileName, dErr)
+	}
+	return config
+}
@@ -0,0 +1,154 @@
+package twitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// extRecording is the file extension of a recorded response's metadata
+	extRecording = ".json"
+	// extRecordingBody is the file extension of a recorded response's body
+	extRecordingBody = ".body"
+)
+
+// recording represents the metadata of an HTTP response saved to disk
+type recording struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+}
+
+// NewRecorder returns an http.RoundTripper that saves every response received from next to dir so it
+// can later be served by a replayer
+func NewRecorder(dir string, next http.RoundTripper) (http.RoundTripper, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recorder{dir: dir, next: next}, nil
+}
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// The body is replaced by the recorded file, which the caller closes
+	body := resp.Body
+	defer func() {
+		_ = body.Close()
+	}()
+
+	// Request headers are not saved so that credentials are never written to disk
+	rec := recording{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+	}
+	b, jErr := json.MarshalIndent(rec, "", "  ")
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	key := recordingKey(req)
+	wErr := os.WriteFile(filepath.Join(r.dir, key+extRecording), b, 0o600)
+	if wErr != nil {
+		return nil, fmt.Errorf("failed to record response for %s: %w", req.URL, wErr)
+	}
+
+	// Stream the body to disk rather than reading it into memory, as media files can be large, and
+	// serve the response from the recorded file
+	bodyFileName := filepath.Join(r.dir, key+extRecordingBody)
+	f, fErr := os.OpenFile(filepath.Clean(bodyFileName), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if fErr != nil {
+		return nil, fmt.Errorf("failed to record response for %s: %w", req.URL, fErr)
+	}
+	size, cErr := io.Copy(f, body)
+	if cErr == nil {
+		_, cErr = f.Seek(0, io.SeekStart)
+	}
+	if cErr != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to record response for %s: %w", req.URL, cErr)
+	}
+
+	resp.Body = f
+	resp.ContentLength = size
+	return resp, nil
+}
+
+// NewReplayer returns an http.RoundTripper that serves responses previously saved to dir by a recorder
+// without making any network requests
+func NewReplayer(dir string) (http.RoundTripper, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &replayer{dir: dir}, nil
+}
+
+type replayer struct {
+	dir string
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := recordingKey(req)
+
+	b, err := os.ReadFile(filepath.Join(r.dir, key+extRecording))
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	rec := recording{}
+	jErr := json.Unmarshal(b, &rec)
+	if jErr != nil {
+		return nil, fmt.Errorf("failed to parse recorded response for %s %s: %w", req.Method, req.URL, jErr)
+	}
+
+	body, bErr := os.Open(filepath.Join(r.dir, key+extRecordingBody))
+	if bErr != nil {
+		return nil, fmt.Errorf("failed to read recorded response body for %s %s: %w", req.Method, req.URL, bErr)
+	}
+	info, sErr := body.Stat()
+	if sErr != nil {
+		_ = body.Close()
+		return nil, fmt.Errorf("failed to read recorded response body for %s %s: %w", req.Method, req.URL, sErr)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header,
+		Body:          body,
+		ContentLength: info.Size(),
+		Request:       req,
+	}, nil
+}
+
+// recordingKey identifies the recording of a request by hashing its method and URL
+func recordingKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return hex.EncodeToString(sum[:])[:32]
+}
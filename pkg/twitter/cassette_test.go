@@ -0,0 +1,154 @@
+package twitter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cassette")
+	server := twittertest.NewServer(fixturesFeatures)
+	host := server.URL
+
+	recorder, err := twitter.NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recordingClient, err := twitter.NewClient("secret-token", twitter.Options{Host: host, HTTPClient: &http.Client{Transport: recorder}})
+	if err != nil {
+		t.Fatalf("failed to construct client: %v", err)
+	}
+	recorded, lErr := recordingClient.LookupTweet("1700000000000000003")
+	if lErr != nil {
+		t.Fatalf("unexpected error: %v", lErr)
+	}
+	recordedSearch, sErr := recordingClient.(twitter.ConversationSearcher).SearchConversation("1700000000000000001")
+	if sErr != nil {
+		t.Fatalf("unexpected error: %v", sErr)
+	}
+
+	// Replaying does not depend on the server
+	server.Close()
+
+	replayer, err := twitter.NewReplayer(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replayingClient, err := twitter.NewClient("", twitter.Options{Host: host, HTTPClient: &http.Client{Transport: replayer}})
+	if err != nil {
+		t.Fatalf("failed to construct client: %v", err)
+	}
+	replayed, lErr := replayingClient.LookupTweet("1700000000000000003")
+	if lErr != nil {
+		t.Fatalf("unexpected error: %v", lErr)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("expected %+v, got %+v", recorded, replayed)
+	}
+	replayedSearch, sErr := replayingClient.(twitter.ConversationSearcher).SearchConversation("1700000000000000001")
+	if sErr != nil {
+		t.Fatalf("unexpected error: %v", sErr)
+	}
+	if !reflect.DeepEqual(replayedSearch, recordedSearch) {
+		t.Errorf("expected %d tweets from search, got %d", len(recordedSearch), len(replayedSearch))
+	}
+
+	// A request that was not recorded fails rather than reaching the network
+	if _, err := replayingClient.LookupTweet("1700000000000000002"); err == nil {
+		t.Error("expected error for request missing from the recording")
+	}
+
+	// Credentials are never written to the recording
+	entries, rErr := os.ReadDir(dir)
+	if rErr != nil {
+		t.Fatalf("unexpected error: %v", rErr)
+	}
+	if len(entries) == 0 {
+		t.Fatal("expected recorded responses")
+	}
+	for _, entry := range entries {
+		b, fErr := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if fErr != nil {
+			t.Fatalf("unexpected error: %v", fErr)
+		}
+		if strings.Contains(string(b), "secret-token") {
+			t.Errorf("expected %s not to contain the token", entry.Name())
+		}
+	}
+}
+
+func TestNewReplayerErrors(t *testing.T) {
+	tests := map[string]struct {
+		dir func(t *testing.T) string
+	}{
+		"missing directory": {
+			dir: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "cassette")
+			},
+		},
+		"file": {
+			dir: func(t *testing.T) string {
+				fileName := filepath.Join(t.TempDir(), "cassette")
+				if err := os.WriteFile(fileName, []byte{}, 0o600); err != nil {
+					t.Fatalf("failed to write %s: %v", fileName, err)
+				}
+				return fileName
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := twitter.NewReplayer(test.dir(t)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRecordAndReplayDownload(t *testing.T) {
+	contents := strings.Repeat("video", 100000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(contents))
+	}))
+	cassette := filepath.Join(t.TempDir(), "cassette")
+
+	recorder, err := twitter.NewRecorder(cassette, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replayer, err := twitter.NewReplayer(cassette)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	transports := []http.RoundTripper{recorder, replayer}
+	for i, transport := range transports {
+		if i > 0 {
+			server.Close()
+		}
+		dir := t.TempDir()
+		a := twitter.Attachment{MediaKey: "7_1", Type: twitter.MediaTypeVideo, URL: server.URL + "/video.mp4"}
+		if dErr := a.Download(&http.Client{Transport: transport}, dir, "1", twitter.ImageSizeDefault); dErr != nil {
+			t.Fatalf("unexpected error: %v", dErr)
+		}
+		if a.Size != int64(len(contents)) {
+			t.Errorf("expected size %d, got %d", len(contents), a.Size)
+		}
+		b, rErr := os.ReadFile(filepath.Join(dir, a.Name("1")))
+		if rErr != nil {
+			t.Fatalf("unexpected error: %v", rErr)
+		}
+		if string(b) != contents {
+			t.Errorf("expected downloaded file to match the served file")
+		}
+	}
+}
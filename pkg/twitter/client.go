@@ -2,8 +2,11 @@ package twitter
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	tw "github.com/g8rswimmer/go-twitter/v2"
 )
@@ -13,53 +16,261 @@ type Client interface {
 	LookupTweet(id string) (*Tweet, error)
 }
 
-// NewClient constructs a Client for querying the Twitter API
-func NewClient(token string) Client {
+// Authorizer adds authorization to Twitter API requests
+type Authorizer interface {
+	Add(req *http.Request)
+}
+
+// FallibleAuthorizer is an Authorizer that can fail to obtain credentials, such as when a saved token
+// cannot be refreshed, in which case the error is returned instead of making an unauthorized request
+type FallibleAuthorizer interface {
+	Authorizer
+	Authorize(req *http.Request) error
+}
+
+// NewClient constructs a Client for querying the Twitter API using an app-only bearer token
+func NewClient(token string, opts Options) (Client, error) {
+	return NewClientWithAuthorizer(authorize{Token: token}, opts)
+}
+
+// NewClientWithAuthorizer constructs a Client for querying the Twitter API using the provided Authorizer
+func NewClientWithAuthorizer(auth Authorizer, opts Options) (Client, error) {
+	host := opts.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	httpClient, err := opts.NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
 	return &twitterClient{
 		c: &tw.Client{
-			Authorizer: authorize{
-				Token: token,
-			},
-			Client: http.DefaultClient,
-			Host:   "https://api.twitter.com",
+			Authorizer: auth,
+			Client:     httpClient,
+			Host:       strings.TrimSuffix(host, "/"),
 		},
-	}
+		limits: newRateLimiter(opts.RateLimitNotify),
+	}, nil
 }
 
+const (
+	// expansionReferencedTweetsMediaKeys includes the media attached to referenced tweets, which is not
+	// defined by the go-twitter library
+	expansionReferencedTweetsMediaKeys tw.Expansion = "referenced_tweets.id.attachments.media_keys"
+	// tweetFieldNoteTweet requests the full text of tweets longer than 280 characters, which is not
+	// defined by the go-twitter library
+	tweetFieldNoteTweet tw.TweetField = "note_tweet"
+
+	// pathLookup is the format of the path of the single tweet lookup endpoint
+	pathLookup = "/2/tweets/%s"
+)
+
 type twitterClient struct {
-	c *tw.Client
+	c      *tw.Client
+	limits *rateLimiter
 }
 
 func (tc *twitterClient) LookupTweet(tweetID string) (*Tweet, error) {
-	tweetResponse, err := tc.c.TweetLookup(context.Background(), []string{tweetID}, tw.TweetLookupOpts{
-		Expansions: []tw.Expansion{
-			tw.ExpansionEntitiesMentionsUserName,
-			tw.ExpansionAuthorID,
-			tw.ExpansionAttachmentsMediaKeys,
-		},
-		MediaFields: []tw.MediaField{
-			tw.MediaFieldMediaKey,
-			tw.MediaFieldURL,
-			tw.MediaFieldType,
-			tw.MediaFieldPreviewImageURL,
-			tw.MediaFieldVariants,
-		},
-		TweetFields: []tw.TweetField{
-			tw.TweetFieldCreatedAt,
-			tw.TweetFieldConversationID,
-			tw.TweetFieldReferencedTweets,
-		},
-	})
+	resp, err := tc.lookup(tweetID)
 	if err != nil {
 		return nil, fmt.Errorf("tweet lookup error: %v", err)
 	}
 
-	tweetDictionary, ok := tweetResponse.Raw.TweetDictionaries()[tweetID]
-	if !ok {
+	// A response without data reports the reason in its errors
+	if resp.Data == nil || resp.Data.ID != tweetID {
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("tweet lookup error: %s", resp.Errors[0].Detail)
+		}
 		return nil, fmt.Errorf("tweet lookup error: response does not include tweet with ID %s", tweetID)
 	}
 
-	return ParseTweet(tweetDictionary)
+	includes, notes := resp.Includes.split()
+	notes[resp.Data.ID] = resp.Data.NoteTweet
+
+	tweet, pErr := ParseTweet(tw.CreateTweetDictionary(resp.Data.TweetObj, includes))
+	if pErr != nil {
+		return nil, pErr
+	}
+	tweet.setNote(notes[tweet.ID])
+	if tweet.QuotedTweet != nil {
+		tweet.QuotedTweet.setNote(notes[tweet.QuotedTweet.ID])
+	}
+	return tweet, nil
+}
+
+// lookup queries the single tweet lookup endpoint, decoding the response separately from the go-twitter
+// library so that fields it does not support, such as note_tweet, are kept
+func (tc *twitterClient) lookup(tweetID string) (*tweetLookupResponse, error) {
+	lookupResp := &tweetLookupResponse{}
+	err := tc.get(pathLookup, fmt.Sprintf(pathLookup, tweetID), tweetLookupQuery(), lookupResp)
+	if err != nil {
+		return nil, err
+	}
+	return lookupResp, nil
+}
+
+// get queries the path of an endpoint of the Twitter API and decodes a successful response into v,
+// waiting for the endpoint's rate limit to reset if it is exhausted and retrying requests rejected for
+// exceeding it
+func (tc *twitterClient) get(endpoint string, path string, query url.Values, v any) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, tc.c.Host+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	if authorizer, ok := tc.c.Authorizer.(FallibleAuthorizer); ok {
+		aErr := authorizer.Authorize(req)
+		if aErr != nil {
+			return aErr
+		}
+	} else {
+		tc.c.Authorizer.Add(req)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	var resp *http.Response
+	for retries := 0; ; retries++ {
+		tc.limits.wait(endpoint)
+
+		r, dErr := tc.c.Client.Do(req)
+		if dErr != nil {
+			return dErr
+		}
+		exhausted := tc.limits.update(endpoint, r.Header)
+		if r.StatusCode != http.StatusTooManyRequests || !exhausted || retries == maxRateLimitRetries {
+			resp = r
+			break
+		}
+		_ = r.Body.Close()
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	decoder := json.NewDecoder(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		e := &tw.ErrorResponse{}
+		if err := decoder.Decode(e); err != nil {
+			return fmt.Errorf("request failed with status %s", resp.Status)
+		}
+		e.StatusCode = resp.StatusCode
+		return e
+	}
+
+	jErr := decoder.Decode(v)
+	if jErr != nil {
+		return fmt.Errorf("failed to decode response: %w", jErr)
+	}
+	return nil
+}
+
+// tweetLookupQuery constructs the query parameters requesting the fields and expansions used for each tweet
+func tweetLookupQuery() url.Values {
+	expansions := []tw.Expansion{
+		tw.ExpansionEntitiesMentionsUserName,
+		tw.ExpansionAuthorID,
+		tw.ExpansionAttachmentsMediaKeys,
+		tw.ExpansionAttachmentsPollIDs,
+		tw.ExpansionReferencedTweetsID,
+		tw.ExpansionReferencedTweetsIDAuthorID,
+		expansionReferencedTweetsMediaKeys,
+	}
+	mediaFields := []tw.MediaField{
+		tw.MediaFieldMediaKey,
+		tw.MediaFieldURL,
+		tw.MediaFieldType,
+		tw.MediaFieldPreviewImageURL,
+		tw.MediaFieldVariants,
+		tw.MediaFieldAltText,
+		tw.MediaFieldWidth,
+		tw.MediaFieldHeight,
+		tw.MediaFieldDurationMS,
+	}
+	pollFields := []tw.PollField{
+		tw.PollFieldID,
+		tw.PollFieldOptions,
+		tw.PollFieldDurationMinutes,
+		tw.PollFieldEndDateTime,
+		tw.PollFieldVotingStatus,
+	}
+	userFields := []tw.UserField{
+		tw.UserFieldCreatedAt,
+		tw.UserFieldDescription,
+		tw.UserFieldLocation,
+		tw.UserFieldProfileImageURL,
+		tw.UserFieldProtected,
+		tw.UserFieldPublicMetrics,
+		tw.UserFieldVerified,
+	}
+	tweetFields := []tw.TweetField{
+		tw.TweetFieldCreatedAt,
+		tw.TweetFieldConversationID,
+		tw.TweetFieldReferencedTweets,
+		tw.TweetFieldEntities,
+		tw.TweetFieldPublicMetrics,
+		tweetFieldNoteTweet,
+	}
+
+	return url.Values{
+		"expansions":   {joinFields(expansions)},
+		"media.fields": {joinFields(mediaFields)},
+		"poll.fields":  {joinFields(pollFields)},
+		"tweet.fields": {joinFields(tweetFields)},
+		"user.fields":  {joinFields(userFields)},
+	}
+}
+
+func joinFields[T ~string](fields []T) string {
+	strs := make([]string, len(fields))
+	for i, field := range fields {
+		strs[i] = string(field)
+	}
+	return strings.Join(strs, ",")
+}
+
+// tweetLookupResponse is a response of the single tweet lookup endpoint
+type tweetLookupResponse struct {
+	Data     *lookupTweet    `json:"data"`
+	Includes *lookupIncludes `json:"includes"`
+	Errors   []*tw.ErrorObj  `json:"errors"`
+}
+
+// lookupTweet is a tweet object with the fields not supported by the go-twitter library
+type lookupTweet struct {
+	tw.TweetObj
+	NoteTweet *noteTweet `json:"note_tweet,omitempty"`
+}
+
+// lookupIncludes are the expanded objects of a response, with included tweets keeping the fields not
+// supported by the go-twitter library
+type lookupIncludes struct {
+	tw.TweetRawIncludes
+	Tweets []*lookupTweet `json:"tweets,omitempty"`
+}
+
+// split returns the includes in the form used by the go-twitter library along with the note tweets of
+// included tweets by ID
+func (i *lookupIncludes) split() (*tw.TweetRawIncludes, map[string]*noteTweet) {
+	notes := map[string]*noteTweet{}
+	if i == nil {
+		return &tw.TweetRawIncludes{}, notes
+	}
+
+	includes := i.TweetRawIncludes
+	includes.Tweets = []*tw.TweetObj{}
+	for _, tweet := range i.Tweets {
+		includes.Tweets = append(includes.Tweets, &tweet.TweetObj)
+		notes[tweet.ID] = tweet.NoteTweet
+	}
+	return &includes, notes
+}
+
+// noteTweet holds the full text of a tweet longer than 280 characters, whose text field is truncated
+type noteTweet struct {
+	Text     string          `json:"text"`
+	Entities *tw.EntitiesObj `json:"entities,omitempty"`
 }
 
 type authorize struct {
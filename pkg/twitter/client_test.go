@@ -0,0 +1,236 @@
+package twitter_test
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+const (
+	fixturesNathanMacKinnon = "twittertest/testdata/Nathan_MacKinnon_2018"
+	fixturesFeatures        = "twittertest/testdata/features"
+)
+
+func TestLookupTweet(t *testing.T) {
+	tests := map[string]struct {
+		fixtures        string
+		id              string
+		expectedAuthor  string
+		expectedReplied []string
+		expectedMedia   []string
+		expectedPoll    bool
+		expectedQuoted  string
+		// expectedQuotedNote is whether the quoted tweet's full text is kept from its note tweet
+		expectedQuotedNote bool
+	}{
+		"first tweet of thread quoting another author": {
+			fixtures:           fixturesNathanMacKinnon,
+			id:                 "969990878944149504",
+			expectedAuthor:     "Avalanche",
+			expectedMedia:      []string{twitter.MediaTypePhoto},
+			expectedQuoted:     "969853236456411137",
+			expectedQuotedNote: true,
+		},
+		"reply with animated GIF": {
+			fixtures:        fixturesNathanMacKinnon,
+			id:              "969990884300267521",
+			expectedAuthor:  "Avalanche",
+			expectedReplied: []string{"969990878944149504"},
+			expectedMedia:   []string{twitter.MediaTypeGIF},
+		},
+		"poll": {
+			fixtures:     fixturesFeatures,
+			id:           "1700000000000000001",
+			expectedPoll: true,
+		},
+		"reply with photo": {
+			fixtures:        fixturesFeatures,
+			id:              "1700000000000000003",
+			expectedAuthor:  "thread_safe_dev",
+			expectedReplied: []string{"1700000000000000002"},
+			expectedMedia:   []string{twitter.MediaTypePhoto},
+		},
+		"reply to two tweets": {
+			fixtures:        fixturesFeatures,
+			id:              "1700000000000000007",
+			expectedAuthor:  "thread_safe_dev",
+			expectedReplied: []string{"1700000000000000006", "1700000000000000050"},
+		},
+		"reply by another author": {
+			fixtures:        fixturesFeatures,
+			id:              "1700000000000000008",
+			expectedAuthor:  "thread_safe_docs",
+			expectedReplied: []string{"1700000000000000007"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := twittertest.NewClient(t, test.fixtures)
+
+			tweet, err := client.LookupTweet(test.id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tweet.ID != test.id {
+				t.Errorf("expected ID %s, got %s", test.id, tweet.ID)
+			}
+			if test.expectedAuthor != "" && tweet.AuthorHandle != test.expectedAuthor {
+				t.Errorf("expected author %s, got %s", test.expectedAuthor, tweet.AuthorHandle)
+			}
+			repliedToIDs := append([]string{}, tweet.RepliedToIDs...)
+			if !reflect.DeepEqual(repliedToIDs, append([]string{}, test.expectedReplied...)) {
+				t.Errorf("expected replied to IDs %v, got %v", test.expectedReplied, tweet.RepliedToIDs)
+			}
+			mediaTypes := []string{}
+			for _, attachment := range tweet.Attachments {
+				mediaTypes = append(mediaTypes, attachment.Type)
+			}
+			if !reflect.DeepEqual(mediaTypes, append([]string{}, test.expectedMedia...)) {
+				t.Errorf("expected attachments of types %v, got %v", test.expectedMedia, mediaTypes)
+			}
+			if (tweet.Poll != nil) != test.expectedPoll {
+				t.Errorf("expected poll %t, got %+v", test.expectedPoll, tweet.Poll)
+			}
+			quotedID, quotedNote := "", false
+			if tweet.QuotedTweet != nil {
+				quotedID, quotedNote = tweet.QuotedTweet.ID, tweet.QuotedTweet.NoteText != ""
+			}
+			if quotedID != test.expectedQuoted {
+				t.Errorf("expected quoted tweet %q, got %q", test.expectedQuoted, quotedID)
+			}
+			if quotedNote != test.expectedQuotedNote {
+				t.Errorf("expected quoted tweet note text %t, got %t", test.expectedQuotedNote, quotedNote)
+			}
+		})
+	}
+}
+
+func TestLookupTweetErrors(t *testing.T) {
+	tests := map[string]struct {
+		token         string
+		id            string
+		expectedError string
+	}{
+		"unknown tweet": {
+			token:         "token",
+			id:            "1",
+			expectedError: "Could not find tweet with id: [1].",
+		},
+		"missing token": {
+			token:         "",
+			id:            "1700000000000000001",
+			expectedError: "tweet lookup error",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := twittertest.NewServer(fixturesFeatures)
+			defer server.Close()
+			client, err := twitter.NewClient(test.token, twitter.Options{Host: server.URL})
+			if err != nil {
+				t.Fatalf("failed to construct client: %v", err)
+			}
+
+			_, lErr := client.LookupTweet(test.id)
+			if lErr == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(lErr.Error(), test.expectedError) {
+				t.Errorf("expected error containing %q, got %q", test.expectedError, lErr)
+			}
+		})
+	}
+}
+
+func TestSearchConversation(t *testing.T) {
+	client := twittertest.NewClient(t, fixturesFeatures)
+	searcher, ok := client.(twitter.ConversationSearcher)
+	if !ok {
+		t.Fatal("expected client to search conversations")
+	}
+
+	tweets, err := searcher.SearchConversation("1700000000000000001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Both pages of results are returned
+	if len(tweets) != 7 {
+		t.Fatalf("expected 7 tweets, got %d", len(tweets))
+	}
+	for _, tweet := range tweets {
+		if tweet.ConversationID != "1700000000000000001" {
+			t.Errorf("expected tweet %s to be in conversation 1700000000000000001, got %s", tweet.ID, tweet.ConversationID)
+		}
+	}
+}
+
+func TestRateLimitedLookup(t *testing.T) {
+	tests := map[string]struct {
+		// exhaust is whether another client exhausts the rate limit first, so that the lookup is rejected
+		// rather than delayed
+		exhaust bool
+	}{
+		"wait for exhausted limit to reset": {
+			exhaust: false,
+		},
+		"retry rejected request": {
+			exhaust: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(twittertest.NewRateLimitedHandler(
+				twittertest.NewHandler(fixturesFeatures), 1, time.Second,
+			))
+			defer server.Close()
+
+			mu := sync.Mutex{}
+			notified := 0
+			client, err := twitter.NewClient("token", twitter.Options{
+				Host: server.URL,
+				RateLimitNotify: func(_ time.Time) {
+					mu.Lock()
+					defer mu.Unlock()
+					notified++
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to construct client: %v", err)
+			}
+
+			first := client
+			if test.exhaust {
+				other, oErr := twitter.NewClient("token", twitter.Options{Host: server.URL})
+				if oErr != nil {
+					t.Fatalf("failed to construct client: %v", oErr)
+				}
+				first = other
+			}
+			if _, lErr := first.LookupTweet("1700000000000000001"); lErr != nil {
+				t.Fatalf("unexpected error: %v", lErr)
+			}
+
+			tweet, lErr := client.LookupTweet("1700000000000000002")
+			if lErr != nil {
+				t.Fatalf("unexpected error: %v", lErr)
+			}
+			if tweet.ID != "1700000000000000002" {
+				t.Errorf("expected tweet 1700000000000000002, got %s", tweet.ID)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if notified != 1 {
+				t.Errorf("expected 1 rate limit notification, got %d", notified)
+			}
+		})
+	}
+}
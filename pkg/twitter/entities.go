@@ -0,0 +1,209 @@
+package twitter
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+
+	tw "github.com/g8rswimmer/go-twitter/v2"
+)
+
+// Entities holds the links, mentions, hashtags, and cashtags parsed from a Tweet's text
+type Entities struct {
+	URLs     []URLEntity     `json:"urls,omitempty"`
+	Mentions []MentionEntity `json:"mentions,omitempty"`
+	Hashtags []TagEntity     `json:"hashtags,omitempty"`
+	Cashtags []TagEntity     `json:"cashtags,omitempty"`
+}
+
+// URLEntity represents a shortened t.co link and the URL it expands to
+type URLEntity struct {
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	URL         string `json:"url"`
+	ExpandedURL string `json:"expanded_url"`
+	DisplayURL  string `json:"display_url"`
+}
+
+// MentionEntity represents a mention of a user by handle
+type MentionEntity struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Handle string `json:"handle"`
+}
+
+// TagEntity represents a hashtag or cashtag, stored without its leading symbol
+type TagEntity struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Tag   string `json:"tag"`
+}
+
+// parseEntities constructs Entities from the entities returned by the Twitter API
+func parseEntities(raw *tw.EntitiesObj) Entities {
+	entities := Entities{}
+	if raw == nil {
+		return entities
+	}
+
+	for _, u := range raw.URLs {
+		entities.URLs = append(entities.URLs, URLEntity{
+			Start:       u.Start,
+			End:         u.End,
+			URL:         u.URL,
+			ExpandedURL: u.ExpandedURL,
+			DisplayURL:  u.DisplayURL,
+		})
+	}
+	for _, m := range raw.Mentions {
+		entities.Mentions = append(entities.Mentions, MentionEntity{Start: m.Start, End: m.End, Handle: m.UserName})
+	}
+	for _, h := range raw.HashTags {
+		entities.Hashtags = append(entities.Hashtags, TagEntity{Start: h.Start, End: h.End, Tag: h.Tag})
+	}
+	for _, c := range raw.CashTags {
+		entities.Cashtags = append(entities.Cashtags, TagEntity{Start: c.Start, End: c.End, Tag: c.Tag})
+	}
+	return entities
+}
+
+// ExpandText returns text with its t.co links replaced by the URLs they expand to
+func (e Entities) ExpandText(text string) string {
+	return e.replace(text, false)
+}
+
+// HTML returns text as HTML-escaped markup with t.co links replaced by links to the URLs they
+// expand to and with mentions, hashtags, and cashtags linked to their pages on Twitter
+func (e Entities) HTML(text string) string {
+	return e.replace(text, true)
+}
+
+// entitySpan is the location of an entity within a text and its replacement
+type entitySpan struct {
+	start   int
+	end     int
+	literal string // Text expected at the entity's location
+	plain   string // Replacement in plain text, empty to leave the entity's text unchanged
+	link    string // Link target in HTML
+	display string // Link text in HTML, empty to use the entity's text
+}
+
+func (e Entities) replace(text string, asHTML bool) string {
+	spans := []entitySpan{}
+	for _, u := range e.URLs {
+		if u.ExpandedURL == "" {
+			continue
+		}
+		display := u.DisplayURL
+		if display == "" {
+			display = u.ExpandedURL
+		}
+		spans = append(spans, entitySpan{
+			start: u.Start, end: u.End, literal: u.URL, plain: u.ExpandedURL, link: u.ExpandedURL, display: display,
+		})
+	}
+	for _, m := range e.Mentions {
+		spans = append(spans, entitySpan{
+			start: m.Start, end: m.End, literal: "@" + m.Handle, link: "https://twitter.com/" + url.PathEscape(m.Handle),
+		})
+	}
+	for _, h := range e.Hashtags {
+		spans = append(spans, entitySpan{
+			start: h.Start, end: h.End, literal: "#" + h.Tag, link: "https://twitter.com/hashtag/" + url.PathEscape(h.Tag),
+		})
+	}
+	for _, c := range e.Cashtags {
+		spans = append(spans, entitySpan{
+			start: c.Start, end: c.End, literal: "$" + c.Tag, link: "https://twitter.com/search?q=" + url.QueryEscape("$"+c.Tag),
+		})
+	}
+
+	runes := []rune(text)
+	spans = locateSpans(runes, spans)
+
+	escape := func(s string) string {
+		if !asHTML {
+			return s
+		}
+		// Text from the API escapes &, <, and > so unescape first to avoid escaping twice
+		return html.EscapeString(html.UnescapeString(s))
+	}
+
+	b := strings.Builder{}
+	cur := 0
+	for _, span := range spans {
+		b.WriteString(escape(string(runes[cur:span.start])))
+		original := string(runes[span.start:span.end])
+		switch {
+		case asHTML:
+			display := span.display
+			if display == "" {
+				display = original
+			}
+			b.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(span.link), escape(display)))
+		case span.plain != "":
+			b.WriteString(span.plain)
+		default:
+			b.WriteString(original)
+		}
+		cur = span.end
+	}
+	b.WriteString(escape(string(runes[cur:])))
+	return b.String()
+}
+
+// locateSpans returns the spans found in text ordered by position, dropping spans that cannot be found
+// or that overlap an earlier span. The API's indices are used when the expected text is found at that
+// location and the first unclaimed occurrence of the text is used otherwise, as indices can be offset
+// by escaped characters or differ between the API and archive exports.
+func locateSpans(runes []rune, spans []entitySpan) []entitySpan {
+	claimed := make([]bool, len(runes))
+	isFree := func(start, end int) bool {
+		for i := start; i < end; i++ {
+			if claimed[i] {
+				return false
+			}
+		}
+		return true
+	}
+	matches := func(start int, literal []rune) bool {
+		end := start + len(literal)
+		return start >= 0 && end <= len(runes) && strings.EqualFold(string(runes[start:end]), string(literal)) && isFree(start, end)
+	}
+
+	located := []entitySpan{}
+	for _, span := range spans {
+		literal := []rune(span.literal)
+		if len(literal) == 0 {
+			continue
+		}
+
+		start := -1
+		if matches(span.start, literal) {
+			start = span.start
+		} else {
+			for i := range runes {
+				if matches(i, literal) {
+					start = i
+					break
+				}
+			}
+		}
+		if start < 0 {
+			continue
+		}
+
+		span.start, span.end = start, start+len(literal)
+		for i := span.start; i < span.end; i++ {
+			claimed[i] = true
+		}
+		located = append(located, span)
+	}
+
+	sort.Slice(located, func(i, j int) bool {
+		return located[i].start < located[j].start
+	})
+	return located
+}
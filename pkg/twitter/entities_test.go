@@ -0,0 +1,150 @@
+package twitter
+
+import (
+	"testing"
+)
+
+func TestEntities(t *testing.T) {
+	tests := map[string]struct {
+		text             string
+		entities         Entities
+		expectedExpanded string
+		expectedHTML     string
+	}{
+		"no entities": {
+			text:             "Just text",
+			expectedExpanded: "Just text",
+			expectedHTML:     "Just text",
+		},
+		"link": {
+			text: "Read https://t.co/abc now",
+			entities: Entities{URLs: []URLEntity{
+				{Start: 5, End: 21, URL: "https://t.co/abc", ExpandedURL: "https://example.com/a?b=1&c=2", DisplayURL: "example.com/a"},
+			}},
+			expectedExpanded: "Read https://example.com/a?b=1&c=2 now",
+			expectedHTML:     `Read <a href="https://example.com/a?b=1&amp;c=2">example.com/a</a> now`,
+		},
+		"link without display URL": {
+			text: "https://t.co/abc",
+			entities: Entities{URLs: []URLEntity{
+				{Start: 0, End: 16, URL: "https://t.co/abc", ExpandedURL: "https://example.com"},
+			}},
+			expectedExpanded: "https://example.com",
+			expectedHTML:     `<a href="https://example.com">https://example.com</a>`,
+		},
+		"mention, hashtag, and cashtag": {
+			text: "@thread_safe_dev #ThreadSafe $TS",
+			entities: Entities{
+				Mentions: []MentionEntity{{Start: 0, End: 16, Handle: "thread_safe_dev"}},
+				Hashtags: []TagEntity{{Start: 17, End: 28, Tag: "ThreadSafe"}},
+				Cashtags: []TagEntity{{Start: 29, End: 32, Tag: "TS"}},
+			},
+			expectedExpanded: "@thread_safe_dev #ThreadSafe $TS",
+			expectedHTML: `<a href="https://twitter.com/thread_safe_dev">@thread_safe_dev</a> ` +
+				`<a href="https://twitter.com/hashtag/ThreadSafe">#ThreadSafe</a> ` +
+				`<a href="https://twitter.com/search?q=%24TS">$TS</a>`,
+		},
+		"offsets counted in characters rather than bytes": {
+			text: "🧵 é #ThreadSafe",
+			entities: Entities{
+				Hashtags: []TagEntity{{Start: 4, End: 15, Tag: "ThreadSafe"}},
+			},
+			expectedExpanded: "🧵 é #ThreadSafe",
+			expectedHTML:     `🧵 é <a href="https://twitter.com/hashtag/ThreadSafe">#ThreadSafe</a>`,
+		},
+		"offsets shifted by escaped characters": {
+			text: "Q&amp;A #ThreadSafe",
+			entities: Entities{
+				// The API counts "&" as a single character
+				Hashtags: []TagEntity{{Start: 4, End: 15, Tag: "ThreadSafe"}},
+			},
+			expectedExpanded: "Q&amp;A #ThreadSafe",
+			expectedHTML:     `Q&amp;A <a href="https://twitter.com/hashtag/ThreadSafe">#ThreadSafe</a>`,
+		},
+		"repeated entity uses the occurrence at its offsets": {
+			text: "#a and #a",
+			entities: Entities{
+				Hashtags: []TagEntity{{Start: 7, End: 9, Tag: "a"}},
+			},
+			expectedExpanded: "#a and #a",
+			expectedHTML:     `#a and <a href="https://twitter.com/hashtag/a">#a</a>`,
+		},
+		"entity not found in text": {
+			text: "Nothing to link",
+			entities: Entities{
+				Mentions: []MentionEntity{{Start: 0, End: 8, Handle: "someone"}},
+			},
+			expectedExpanded: "Nothing to link",
+			expectedHTML:     "Nothing to link",
+		},
+		"overlapping entity": {
+			text: "https://t.co/abc",
+			entities: Entities{
+				URLs:     []URLEntity{{Start: 0, End: 16, URL: "https://t.co/abc", ExpandedURL: "https://example.com"}},
+				Hashtags: []TagEntity{{Start: 0, End: 16, Tag: "abc"}},
+			},
+			expectedExpanded: "https://example.com",
+			expectedHTML:     `<a href="https://example.com">https://example.com</a>`,
+		},
+		"markup escaped": {
+			text: `<script>alert("x")</script> &lt;b&gt; #tag`,
+			entities: Entities{
+				Hashtags: []TagEntity{{Start: 38, End: 42, Tag: "tag"}},
+			},
+			expectedExpanded: `<script>alert("x")</script> &lt;b&gt; #tag`,
+			expectedHTML: `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &lt;b&gt; ` +
+				`<a href="https://twitter.com/hashtag/tag">#tag</a>`,
+		},
+		"link target escaped": {
+			text: "https://t.co/abc",
+			entities: Entities{URLs: []URLEntity{
+				{Start: 0, End: 16, URL: "https://t.co/abc", ExpandedURL: `https://example.com/"><script>`, DisplayURL: "<b>"},
+			}},
+			expectedExpanded: `https://example.com/"><script>`,
+			expectedHTML:     `<a href="https://example.com/&#34;&gt;&lt;script&gt;">&lt;b&gt;</a>`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := test.entities.ExpandText(test.text); got != test.expectedExpanded {
+				t.Errorf("expected expanded text %q, got %q", test.expectedExpanded, got)
+			}
+			if got := test.entities.HTML(test.text); got != test.expectedHTML {
+				t.Errorf("expected HTML %q, got %q", test.expectedHTML, got)
+			}
+		})
+	}
+}
+
+func TestTweetFullText(t *testing.T) {
+	noteEntities := &Entities{Hashtags: []TagEntity{{Start: 10, End: 15, Tag: "long"}}}
+	tests := map[string]struct {
+		tweet        *Tweet
+		expectedHTML string
+	}{
+		"text": {
+			tweet: &Tweet{
+				Text:     "Short #tag",
+				Entities: Entities{Hashtags: []TagEntity{{Start: 6, End: 10, Tag: "tag"}}},
+			},
+			expectedHTML: `Short <a href="https://twitter.com/hashtag/tag">#tag</a>`,
+		},
+		"note text": {
+			tweet: &Tweet{
+				Text:         "Truncated…",
+				NoteText:     "Full text #long",
+				NoteEntities: noteEntities,
+			},
+			expectedHTML: `Full text <a href="https://twitter.com/hashtag/long">#long</a>`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := test.tweet.HTML(); got != test.expectedHTML {
+				t.Errorf("expected HTML %q, got %q", test.expectedHTML, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package twitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Record sets the size and SHA-256 digest of an Attachment from its saved file, for Attachments that
+// are saved without being downloaded
+func (a *Attachment) Record(fileName string) error {
+	size, digest, err := fileDigest(fileName)
+	if err != nil {
+		return err
+	}
+	a.Size, a.SHA256 = size, digest
+	return nil
+}
+
+// IsRecorded evaluates if the size and digest of an Attachment's saved file were recorded, which is
+// not the case for Attachments that were not saved or were saved by older versions
+func (a Attachment) IsRecorded() bool {
+	return a.SHA256 != ""
+}
+
+// Verify checks that an Attachment's saved file exists and matches its recorded size and digest, if
+// they were recorded
+func (a Attachment) Verify(fileName string) error {
+	if !a.IsRecorded() {
+		_, err := os.Stat(fileName)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file %s is missing", fileName)
+		}
+		return err
+	}
+
+	size, digest, err := fileDigest(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file %s is missing", fileName)
+		}
+		return err
+	}
+	if size != a.Size {
+		return fmt.Errorf("file %s has size %d, expected %d", fileName, size, a.Size)
+	}
+	if digest != a.SHA256 {
+		return fmt.Errorf("file %s has SHA-256 %s, expected %s", fileName, digest, a.SHA256)
+	}
+	return nil
+}
+
+// fileDigest returns the size and hex-encoded SHA-256 digest of a file
+func fileDigest(fileName string) (int64, string, error) {
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	n, cErr := io.Copy(h, f)
+	if cErr != nil {
+		return 0, "", cErr
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
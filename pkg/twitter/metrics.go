@@ -0,0 +1,29 @@
+package twitter
+
+import (
+	tw "github.com/g8rswimmer/go-twitter/v2"
+)
+
+// TweetMetrics are the public engagement counts of a Tweet at the time it was looked up
+type TweetMetrics struct {
+	Replies     int `json:"replies"`
+	Retweets    int `json:"retweets"`
+	Quotes      int `json:"quotes"`
+	Likes       int `json:"likes"`
+	Impressions int `json:"impressions,omitempty"`
+}
+
+// parseTweetMetrics constructs TweetMetrics from the public metrics returned by the Twitter API,
+// returning nil if the metrics were not returned
+func parseTweetMetrics(raw *tw.TweetMetricsObj) *TweetMetrics {
+	if raw == nil {
+		return nil
+	}
+	return &TweetMetrics{
+		Replies:     raw.Replies,
+		Retweets:    raw.Retweets,
+		Quotes:      raw.Quotes,
+		Likes:       raw.Likes,
+		Impressions: raw.Impressions,
+	}
+}
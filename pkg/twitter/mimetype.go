@@ -0,0 +1,88 @@
+package twitter
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of bytes read from the beginning of a file to detect its MIME type
+const sniffLen = 512
+
+// mimeTypeExtensions maps the MIME types of media files to the extensions used to name them
+var mimeTypeExtensions = map[string]string{
+	"image/gif":       ".gif",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+	"video/webm":      ".webm",
+}
+
+// MIMETypeByExtension returns the MIME type of a media file with extension ext, or an empty string if
+// the extension is not recognized
+func MIMETypeByExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext == ".jpeg" {
+		return "image/jpeg"
+	}
+	for mimeType, e := range mimeTypeExtensions {
+		if e == ext {
+			return mimeType
+		}
+	}
+	return ""
+}
+
+// extensionByMIMEType returns the extension used to name a media file with a MIME type, or an empty
+// string if the MIME type is not recognized
+func extensionByMIMEType(mimeType string) string {
+	return mimeTypeExtensions[mimeType]
+}
+
+// DetectMIMEType sets the MIME type of an Attachment from the contents of a file, for Attachments that
+// are saved without being downloaded
+func (a *Attachment) DetectMIMEType(fileName string) error {
+	f, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	head := make([]byte, sniffLen)
+	n, rErr := io.ReadFull(f, head)
+	if rErr != nil && !errors.Is(rErr, io.EOF) && !errors.Is(rErr, io.ErrUnexpectedEOF) {
+		return rErr
+	}
+	a.MIMEType = detectMIMEType(head[:n], "")
+	return nil
+}
+
+// detectMIMEType detects the MIME type of a media file from the first bytes of its contents, using the
+// Content-Type header it was served with if the contents are not recognized
+func detectMIMEType(head []byte, contentType string) string {
+	if sniffed := http.DetectContentType(head); isMediaType(sniffed) {
+		return sniffed
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && isMediaType(mediaType) {
+		return strings.ToLower(mediaType)
+	}
+	return ""
+}
+
+// isMediaType evaluates if a MIME type is that of an image, video, or audio file
+func isMediaType(mimeType string) bool {
+	for _, prefix := range []string{"image/", "video/", "audio/"} {
+		if strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package twitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	headJPEG = "\xff\xd8\xff\xe0\x00\x10JFIF\x00"
+	headPNG  = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	headMP4  = "\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"
+)
+
+func TestDetectMIMEType(t *testing.T) {
+	tests := map[string]struct {
+		head        string
+		contentType string
+		expected    string
+	}{
+		"contents match content type": {
+			head:        headJPEG,
+			contentType: "image/jpeg",
+			expected:    "image/jpeg",
+		},
+		"contents differ from content type": {
+			head:        headPNG,
+			contentType: "image/jpeg",
+			expected:    "image/png",
+		},
+		"contents without content type": {
+			head:     headMP4,
+			expected: "video/mp4",
+		},
+		"unrecognized contents use content type": {
+			head:        "\x00\x01\x02\x03",
+			contentType: "Video/QuickTime; charset=binary",
+			expected:    "video/quicktime",
+		},
+		"unrecognized contents and non-media content type": {
+			head:        "\x00\x01\x02\x03",
+			contentType: "application/octet-stream",
+			expected:    "",
+		},
+		"text contents": {
+			head:        "<html><body>Not found</body></html>",
+			contentType: "text/html",
+			expected:    "",
+		},
+		"invalid content type": {
+			head:        "\x00\x01\x02\x03",
+			contentType: "image/",
+			expected:    "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if mimeType := detectMIMEType([]byte(test.head), test.contentType); mimeType != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, mimeType)
+			}
+		})
+	}
+}
+
+func TestMIMETypeByExtension(t *testing.T) {
+	tests := map[string]struct {
+		ext      string
+		expected string
+	}{
+		"jpg":       {ext: ".jpg", expected: "image/jpeg"},
+		"jpeg":      {ext: ".jpeg", expected: "image/jpeg"},
+		"uppercase": {ext: ".PNG", expected: "image/png"},
+		"video":     {ext: ".mov", expected: "video/quicktime"},
+		"unknown":   {ext: ".txt", expected: ""},
+		"empty":     {ext: "", expected: ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if mimeType := MIMETypeByExtension(test.ext); mimeType != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, mimeType)
+			}
+		})
+	}
+}
+
+func TestAttachmentName(t *testing.T) {
+	tests := map[string]struct {
+		url      string
+		mimeType string
+		expected string
+	}{
+		"MIME type matches extension": {
+			url:      "https://pbs.twimg.com/media/image.jpg",
+			mimeType: "image/jpeg",
+			expected: "tweet=1-media_key=3_1.jpg",
+		},
+		"MIME type differs from extension": {
+			url:      "https://pbs.twimg.com/media/image.jpg",
+			mimeType: "image/png",
+			expected: "tweet=1-media_key=3_1.png",
+		},
+		"MIME type without extension": {
+			url:      "https://pbs.twimg.com/media/image?format=webp&name=small",
+			mimeType: "image/webp",
+			expected: "tweet=1-media_key=3_1.webp",
+		},
+		"unknown MIME type uses extension": {
+			url:      "https://video.twimg.com/audio.m4a?tag=12",
+			mimeType: "audio/mp4",
+			expected: "tweet=1-media_key=3_1.m4a",
+		},
+		"no MIME type uses extension": {
+			url:      "https://video.twimg.com/video.mp4?tag=12",
+			expected: "tweet=1-media_key=3_1.mp4",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := Attachment{MediaKey: "3_1", URL: test.url, MIMEType: test.mimeType}
+			if fileName := a.Name("1"); fileName != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, fileName)
+			}
+		})
+	}
+}
+
+func TestAttachmentDetectMIMEType(t *testing.T) {
+	tests := map[string]struct {
+		contents string
+		expected string
+	}{
+		"image":        {contents: headPNG, expected: "image/png"},
+		"short file":   {contents: "GIF89a", expected: "image/gif"},
+		"unrecognized": {contents: "plain text", expected: ""},
+		"empty":        {contents: "", expected: ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "media.jpg")
+			if err := os.WriteFile(fileName, []byte(test.contents), 0o600); err != nil {
+				t.Fatalf("failed to write %s: %v", fileName, err)
+			}
+			a := Attachment{MIMEType: "image/jpeg"}
+			if err := a.DetectMIMEType(fileName); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if a.MIMEType != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, a.MIMEType)
+			}
+		})
+	}
+
+	a := Attachment{}
+	if err := a.DetectMIMEType(filepath.Join(t.TempDir(), "missing.jpg")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestDownloadNamesByMIMEType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Served as JPEG from a URL with a .jpg extension although the contents are a PNG
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte(headPNG))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	a := Attachment{MediaKey: "3_1", Type: MediaTypePhoto, URL: server.URL + "/media/image.jpg"}
+	if err := a.Download(server.Client(), dir, "1", ImageSizeDefault); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.MIMEType != "image/png" {
+		t.Errorf("expected MIME type image/png, got %s", a.MIMEType)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tweet=1-media_key=3_1.png")); err != nil {
+		t.Errorf("expected file named by MIME type: %v", err)
+	}
+}
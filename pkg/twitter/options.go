@@ -0,0 +1,129 @@
+package twitter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultHost is the base URL of the Twitter API
+const DefaultHost = "https://api.twitter.com"
+
+// tlsVersions maps supported names of minimum TLS versions to their values
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Options holds optional settings for the network connections used by a Client and for downloading
+// attachments
+type Options struct {
+	Host               string        // Base URL of the Twitter API (DefaultHost if empty)
+	HTTPClient         *http.Client  // Client used for requests (constructed from the remaining options if nil)
+	Timeout            time.Duration // Time limit for each request (no limit if zero)
+	ProxyURL           string        // URL of a proxy server (proxy environment variables are used if empty)
+	CAFile             string        // Path to a PEM file of certificate authorities to trust in addition to the system's
+	UserAgent          string        // User-Agent header value for all requests (Go's default if empty)
+	TLSMinVersion      string        // Minimum TLS version: 1.0, 1.1, 1.2, or 1.3 (Go's default if empty)
+	InsecureSkipVerify bool          // Disable verification of server certificates
+
+	RateLimitNotify func(reset time.Time) // Called before waiting for an exhausted rate limit to reset at the provided time (no notification if nil)
+}
+
+// NewHTTPClient constructs an http.Client configured by Options
+func (o Options) NewHTTPClient() (*http.Client, error) {
+	if o.HTTPClient != nil {
+		return o.HTTPClient, nil
+	}
+	transport, err := o.Transport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   o.Timeout,
+	}, nil
+}
+
+// Transport constructs an http.RoundTripper configured by the proxy, TLS, and user agent Options
+func (o Options) Transport() (http.RoundTripper, error) {
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unexpected type of http.DefaultTransport")
+	}
+	transport := defaultTransport.Clone()
+
+	if o.ProxyURL != "" {
+		proxyURL, err := url.Parse(o.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %s: %w", o.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if o.UserAgent == "" {
+		return transport, nil
+	}
+	return &userAgentTransport{
+		userAgent: o.UserAgent,
+		next:      transport,
+	}, nil
+}
+
+func (o Options) tlsConfig() (*tls.Config, error) {
+	// nolint:gosec // Verification is disabled only when explicitly configured
+	conf := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.TLSMinVersion != "" {
+		version, ok := tlsVersions[o.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minimum TLS version %s", o.TLSMinVersion)
+		}
+		conf.MinVersion = version
+	}
+
+	if o.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, rErr := os.ReadFile(filepath.Clean(o.CAFile))
+		if rErr != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", rErr)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		conf.RootCAs = pool
+	}
+
+	return conf, nil
+}
+
+// userAgentTransport sets the User-Agent header of every request
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
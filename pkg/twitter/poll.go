@@ -0,0 +1,63 @@
+package twitter
+
+import (
+	tw "github.com/g8rswimmer/go-twitter/v2"
+)
+
+// Poll represents a poll attached to a Tweet
+type Poll struct {
+	ID              string       `json:"id"`
+	Options         []PollOption `json:"options"`
+	DurationMinutes int          `json:"duration_minutes,omitempty"`
+	EndTime         string       `json:"end_time,omitempty"`
+	VotingStatus    string       `json:"voting_status,omitempty"`
+}
+
+// PollOption represents a choice in a Poll and the number of votes it received
+type PollOption struct {
+	Position int    `json:"position"`
+	Label    string `json:"label"`
+	Votes    int    `json:"votes"`
+}
+
+// TotalVotes returns the number of votes cast for all of a Poll's options
+func (p *Poll) TotalVotes() int {
+	total := 0
+	for _, option := range p.Options {
+		total += option.Votes
+	}
+	return total
+}
+
+// IsClosed evaluates if a Poll no longer accepts votes
+func (p *Poll) IsClosed() bool {
+	return p.VotingStatus == "closed"
+}
+
+// parsePoll constructs a Poll from the first poll returned by the Twitter API, as a tweet has at most one
+func parsePoll(polls []*tw.PollObj) *Poll {
+	if len(polls) == 0 || polls[0] == nil {
+		return nil
+	}
+	raw := polls[0]
+
+	options := []PollOption{}
+	for _, option := range raw.Options {
+		if option == nil {
+			continue
+		}
+		options = append(options, PollOption{
+			Position: option.Position,
+			Label:    option.Label,
+			Votes:    option.Votes,
+		})
+	}
+
+	return &Poll{
+		ID:              raw.ID,
+		Options:         options,
+		DurationMinutes: raw.DurationMinutes,
+		EndTime:         raw.EndDateTime,
+		VotingStatus:    raw.VotingStatus,
+	}
+}
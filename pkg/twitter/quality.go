@@ -0,0 +1,168 @@
+package twitter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// VideoQualityBest selects the video variant with the largest bit rate
+	VideoQualityBest = "best"
+	// VideoQualityWorst selects the video variant with the smallest bit rate
+	VideoQualityWorst = "worst"
+
+	// ImageSizeDefault is the size of images at the URL returned by the API
+	ImageSizeDefault ImageSize = "default"
+	// ImageSizeOrig is the size of images as they were uploaded
+	ImageSizeOrig ImageSize = "orig"
+
+	// variantContentTypeMP4 is the content type of downloadable video variants, other variants are
+	// streaming playlists
+	variantContentTypeMP4 = "video/mp4"
+)
+
+// Variant represents one of the encodings in which Twitter serves a video or animated GIF
+type Variant struct {
+	URL         string `json:"url"`
+	BitRate     int    `json:"bit_rate"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// VideoQuality selects which of a video's variants is downloaded
+type VideoQuality struct {
+	maxBitRate int // Largest bit rate to select, 0 for no limit and -1 for the smallest bit rate
+}
+
+// ParseVideoQuality parses a VideoQuality from "best", "worst", or a maximum bit rate in bits per
+// second, using "best" for an empty string
+func ParseVideoQuality(s string) (VideoQuality, error) {
+	switch s {
+	case "", VideoQualityBest:
+		return VideoQuality{}, nil
+	case VideoQualityWorst:
+		return VideoQuality{maxBitRate: -1}, nil
+	}
+
+	maxBitRate, err := strconv.Atoi(s)
+	if err != nil || maxBitRate <= 0 {
+		return VideoQuality{}, fmt.Errorf("invalid video quality %s: expected %s, %s, or a positive maximum bit rate",
+			s, VideoQualityBest, VideoQualityWorst)
+	}
+	return VideoQuality{maxBitRate: maxBitRate}, nil
+}
+
+// String returns the representation of a VideoQuality accepted by ParseVideoQuality
+func (q VideoQuality) String() string {
+	switch {
+	case q.maxBitRate == 0:
+		return VideoQualityBest
+	case q.maxBitRate < 0:
+		return VideoQualityWorst
+	default:
+		return strconv.Itoa(q.maxBitRate)
+	}
+}
+
+// Set parses and sets a VideoQuality, implementing flag.Value
+func (q *VideoQuality) Set(s string) error {
+	quality, err := ParseVideoQuality(s)
+	if err != nil {
+		return err
+	}
+	*q = quality
+	return nil
+}
+
+// Select returns the variant with the largest bit rate not exceeding the VideoQuality's maximum,
+// falling back to the variant with the smallest bit rate if all exceed it, and a bool indicating if a
+// downloadable variant exists
+func (q VideoQuality) Select(variants []Variant) (Variant, bool) {
+	var best, worst *Variant
+	for i := range variants {
+		v := &variants[i]
+		if v.URL == "" || (v.ContentType != "" && v.ContentType != variantContentTypeMP4) {
+			continue
+		}
+		if worst == nil || v.BitRate < worst.BitRate {
+			worst = v
+		}
+		if q.maxBitRate > 0 && v.BitRate > q.maxBitRate {
+			continue
+		}
+		if best == nil || v.BitRate >= best.BitRate {
+			best = v
+		}
+	}
+
+	switch {
+	case worst == nil:
+		return Variant{}, false
+	case q.maxBitRate < 0 || best == nil:
+		return *worst, true
+	default:
+		return *best, true
+	}
+}
+
+// WithVideoQuality returns a copy of an Attachment with its URL set to the variant selected by a
+// VideoQuality, leaving Attachments without variants unchanged
+func (a Attachment) WithVideoQuality(q VideoQuality) Attachment {
+	if v, ok := q.Select(a.Variants); ok {
+		a.URL = v.URL
+	}
+	return a
+}
+
+// ImageSize is a named size at which Twitter serves images
+type ImageSize string
+
+// imageSizes are the valid ImageSizes, from smallest to largest
+var imageSizes = []ImageSize{"thumb", "small", "medium", "large", ImageSizeOrig}
+
+// ParseImageSize parses an ImageSize from "default" or one of the sizes served by Twitter, using
+// "default" for an empty string
+func ParseImageSize(s string) (ImageSize, error) {
+	if s == "" || ImageSize(s) == ImageSizeDefault {
+		return ImageSizeDefault, nil
+	}
+	for _, size := range imageSizes {
+		if ImageSize(s) == size {
+			return size, nil
+		}
+	}
+
+	names := []string{string(ImageSizeDefault)}
+	for _, size := range imageSizes {
+		names = append(names, string(size))
+	}
+	return ImageSizeDefault, fmt.Errorf("invalid image size %s: expected one of %s", s, strings.Join(names, ", "))
+}
+
+// String returns the name of an ImageSize
+func (s ImageSize) String() string {
+	return string(s)
+}
+
+// Set parses and sets an ImageSize, implementing flag.Value
+func (s *ImageSize) Set(value string) error {
+	size, err := ParseImageSize(value)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// sizedURL returns an image URL rewritten to request the image at the specified size
+func sizedURL(imageURL string, size ImageSize) (string, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("name", string(size))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,254 @@
+package twitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVideoQualitySelect(t *testing.T) {
+	variants := []Variant{
+		{URL: "https://video.twimg.com/832000.mp4", BitRate: 832000, ContentType: "video/mp4"},
+		{URL: "https://video.twimg.com/playlist.m3u8", ContentType: "application/x-mpegURL"},
+		{URL: "https://video.twimg.com/2176000.mp4", BitRate: 2176000, ContentType: "video/mp4"},
+		{URL: "https://video.twimg.com/256000.mp4", BitRate: 256000, ContentType: "video/mp4"},
+	}
+
+	tests := map[string]struct {
+		quality     string
+		variants    []Variant
+		expectedURL string
+		expectedOK  bool
+	}{
+		"best": {
+			quality:     "best",
+			variants:    variants,
+			expectedURL: "https://video.twimg.com/2176000.mp4",
+			expectedOK:  true,
+		},
+		"worst": {
+			quality:     "worst",
+			variants:    variants,
+			expectedURL: "https://video.twimg.com/256000.mp4",
+			expectedOK:  true,
+		},
+		"maximum between bit rates": {
+			quality:     "1000000",
+			variants:    variants,
+			expectedURL: "https://video.twimg.com/832000.mp4",
+			expectedOK:  true,
+		},
+		"maximum equal to bit rate": {
+			quality:     "832000",
+			variants:    variants,
+			expectedURL: "https://video.twimg.com/832000.mp4",
+			expectedOK:  true,
+		},
+		"maximum below all bit rates": {
+			quality:     "1000",
+			variants:    variants,
+			expectedURL: "https://video.twimg.com/256000.mp4",
+			expectedOK:  true,
+		},
+		"tie selects later variant": {
+			quality: "best",
+			variants: []Variant{
+				{URL: "https://video.twimg.com/first.mp4", BitRate: 832000, ContentType: "video/mp4"},
+				{URL: "https://video.twimg.com/second.mp4", BitRate: 832000, ContentType: "video/mp4"},
+			},
+			expectedURL: "https://video.twimg.com/second.mp4",
+			expectedOK:  true,
+		},
+		"missing bit rate": {
+			quality: "best",
+			variants: []Variant{
+				{URL: "https://video.twimg.com/gif.mp4", ContentType: "video/mp4"},
+			},
+			expectedURL: "https://video.twimg.com/gif.mp4",
+			expectedOK:  true,
+		},
+		"missing bit rate within maximum": {
+			quality: "1000",
+			variants: []Variant{
+				{URL: "https://video.twimg.com/unknown.mp4", ContentType: "video/mp4"},
+				{URL: "https://video.twimg.com/832000.mp4", BitRate: 832000, ContentType: "video/mp4"},
+			},
+			expectedURL: "https://video.twimg.com/unknown.mp4",
+			expectedOK:  true,
+		},
+		"missing content type": {
+			quality: "best",
+			variants: []Variant{
+				{URL: "https://video.twimg.com/832000.mp4", BitRate: 832000, ContentType: "video/mp4"},
+				{URL: "https://video.twimg.com/untyped.mp4", BitRate: 2176000},
+			},
+			expectedURL: "https://video.twimg.com/untyped.mp4",
+			expectedOK:  true,
+		},
+		"missing URL": {
+			quality: "best",
+			variants: []Variant{
+				{URL: "https://video.twimg.com/832000.mp4", BitRate: 832000, ContentType: "video/mp4"},
+				{BitRate: 2176000, ContentType: "video/mp4"},
+			},
+			expectedURL: "https://video.twimg.com/832000.mp4",
+			expectedOK:  true,
+		},
+		"only playlists": {
+			quality: "best",
+			variants: []Variant{
+				{URL: "https://video.twimg.com/playlist.m3u8", ContentType: "application/x-mpegURL"},
+			},
+			expectedOK: false,
+		},
+		"no variants": {
+			quality:    "best",
+			variants:   nil,
+			expectedOK: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			quality, err := ParseVideoQuality(test.quality)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			v, ok := quality.Select(test.variants)
+			if ok != test.expectedOK {
+				t.Fatalf("expected ok %t, got %t", test.expectedOK, ok)
+			}
+			if v.URL != test.expectedURL {
+				t.Errorf("expected %s, got %s", test.expectedURL, v.URL)
+			}
+		})
+	}
+}
+
+func TestParseVideoQuality(t *testing.T) {
+	tests := map[string]struct {
+		s           string
+		expected    string
+		expectedErr bool
+	}{
+		"empty":    {s: "", expected: "best"},
+		"best":     {s: "best", expected: "best"},
+		"worst":    {s: "worst", expected: "worst"},
+		"bit rate": {s: "832000", expected: "832000"},
+		"zero":     {s: "0", expectedErr: true},
+		"negative": {s: "-1", expectedErr: true},
+		"invalid":  {s: "high", expectedErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			quality, err := ParseVideoQuality(test.s)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got %s", quality)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if quality.String() != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, quality)
+			}
+		})
+	}
+}
+
+func TestSizedURL(t *testing.T) {
+	tests := map[string]struct {
+		imageURL    string
+		size        ImageSize
+		expectedURL string
+	}{
+		"without query": {
+			imageURL:    "https://pbs.twimg.com/media/image.jpg",
+			size:        ImageSizeOrig,
+			expectedURL: "https://pbs.twimg.com/media/image.jpg?name=orig",
+		},
+		"without name": {
+			imageURL:    "https://pbs.twimg.com/media/image?format=jpg",
+			size:        "large",
+			expectedURL: "https://pbs.twimg.com/media/image?format=jpg&name=large",
+		},
+		"with name": {
+			imageURL:    "https://pbs.twimg.com/media/image?format=jpg&name=small",
+			size:        ImageSizeOrig,
+			expectedURL: "https://pbs.twimg.com/media/image?format=jpg&name=orig",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sized, err := sizedURL(test.imageURL, test.size)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sized != test.expectedURL {
+				t.Errorf("expected %s, got %s", test.expectedURL, sized)
+			}
+		})
+	}
+}
+
+func TestDownloadImageSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("name") {
+		case "":
+			_, _ = w.Write([]byte("default"))
+		case "orig":
+			_, _ = w.Write([]byte("orig"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := map[string]struct {
+		imageSize         ImageSize
+		expectedContents  string
+		expectedImageSize ImageSize
+	}{
+		"default": {
+			imageSize:         ImageSizeDefault,
+			expectedContents:  "default",
+			expectedImageSize: ImageSizeDefault,
+		},
+		"available size": {
+			imageSize:         ImageSizeOrig,
+			expectedContents:  "orig",
+			expectedImageSize: ImageSizeOrig,
+		},
+		"unavailable size falls back to default": {
+			imageSize:         "large",
+			expectedContents:  "default",
+			expectedImageSize: ImageSizeDefault,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			a := Attachment{MediaKey: "3_1", Type: MediaTypePhoto, URL: server.URL + "/media/image.jpg"}
+			if err := a.Download(server.Client(), dir, "1", test.imageSize); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if a.ImageSize != test.expectedImageSize {
+				t.Errorf("expected image size %s, got %s", test.expectedImageSize, a.ImageSize)
+			}
+			b, err := os.ReadFile(filepath.Join(dir, a.Name("1")))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(b) != test.expectedContents {
+				t.Errorf("expected contents %s, got %s", test.expectedContents, b)
+			}
+		})
+	}
+}
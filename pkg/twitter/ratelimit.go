@@ -0,0 +1,71 @@
+package twitter
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// headerRateLimitRemaining is the response header reporting the number of requests remaining in the
+	// current rate limit window of an endpoint
+	headerRateLimitRemaining = "x-rate-limit-remaining"
+	// headerRateLimitReset is the response header reporting the Unix time at which the current rate limit
+	// window of an endpoint resets
+	headerRateLimitReset = "x-rate-limit-reset"
+	// maxRateLimitRetries is the number of times a request rejected for exceeding a rate limit is retried
+	// once the limit resets
+	maxRateLimitRetries = 3
+)
+
+// rateLimiter tracks the rate limits reported by the responses of each endpoint, delaying requests to an
+// endpoint whose limit is exhausted until the limit resets. A Client's rateLimiter is shared by all of
+// its requests, so a Client reused for saving several threads respects the limits across all of them.
+type rateLimiter struct {
+	mu     sync.Mutex
+	resets map[string]time.Time // Time at which the exhausted limit of each endpoint resets
+	notify func(reset time.Time)
+}
+
+func newRateLimiter(notify func(reset time.Time)) *rateLimiter {
+	return &rateLimiter{
+		resets: map[string]time.Time{},
+		notify: notify,
+	}
+}
+
+// wait blocks until the rate limit of an endpoint resets if it is exhausted
+func (l *rateLimiter) wait(endpoint string) {
+	l.mu.Lock()
+	reset, ok := l.resets[endpoint]
+	delete(l.resets, endpoint)
+	l.mu.Unlock()
+
+	if !ok || !time.Now().Before(reset) {
+		return
+	}
+	if l.notify != nil {
+		l.notify(reset)
+	}
+	time.Sleep(time.Until(reset))
+}
+
+// update records the rate limit of an endpoint reported by a response's headers, returning a bool
+// indicating if the limit is exhausted
+func (l *rateLimiter) update(endpoint string, header http.Header) bool {
+	remaining, err := strconv.Atoi(header.Get(headerRateLimitRemaining))
+	if err != nil || remaining > 0 {
+		return false
+	}
+	resetUnix, rErr := strconv.ParseInt(header.Get(headerRateLimitReset), 10, 64)
+	if rErr != nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// The reset time has a resolution of seconds so wait an additional second to be safe
+	l.resets[endpoint] = time.Unix(resetUnix, 0).Add(time.Second)
+	return true
+}
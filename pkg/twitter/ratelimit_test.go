@@ -0,0 +1,62 @@
+package twitter
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterUpdate(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	tests := map[string]struct {
+		remaining         string
+		reset             string
+		expectedExhausted bool
+	}{
+		"requests remaining": {
+			remaining:         "3",
+			reset:             strconv.FormatInt(reset.Unix(), 10),
+			expectedExhausted: false,
+		},
+		"exhausted": {
+			remaining:         "0",
+			reset:             strconv.FormatInt(reset.Unix(), 10),
+			expectedExhausted: true,
+		},
+		"no headers": {
+			expectedExhausted: false,
+		},
+		"invalid reset": {
+			remaining:         "0",
+			reset:             "soon",
+			expectedExhausted: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{}
+			if test.remaining != "" {
+				header.Set(headerRateLimitRemaining, test.remaining)
+			}
+			if test.reset != "" {
+				header.Set(headerRateLimitReset, test.reset)
+			}
+
+			l := newRateLimiter(nil)
+			exhausted := l.update(pathLookup, header)
+			if exhausted != test.expectedExhausted {
+				t.Errorf("expected exhausted %t, got %t", test.expectedExhausted, exhausted)
+			}
+			recorded, ok := l.resets[pathLookup]
+			if ok != test.expectedExhausted {
+				t.Fatalf("expected reset recorded %t, got %t", test.expectedExhausted, ok)
+			}
+			if ok && !recorded.Equal(reset.Add(time.Second)) {
+				t.Errorf("expected reset %v, got %v", reset.Add(time.Second), recorded)
+			}
+		})
+	}
+}
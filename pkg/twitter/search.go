@@ -0,0 +1,92 @@
+package twitter
+
+import (
+	"fmt"
+	"strconv"
+
+	tw "github.com/g8rswimmer/go-twitter/v2"
+)
+
+const (
+	// pathSearchRecent is the path of the endpoint searching tweets from the last seven days
+	pathSearchRecent = "/2/tweets/search/recent"
+	// maxSearchResults is the number of tweets requested for each page of search results, the maximum
+	// allowed by the API
+	maxSearchResults = 100
+	// maxSearchPages is the maximum number of pages of search results fetched for a conversation
+	maxSearchPages = 10
+)
+
+// ConversationSearcher is the interface for querying all tweets of a conversation
+type ConversationSearcher interface {
+	SearchConversation(conversationID string) ([]*Tweet, error)
+}
+
+// SearchConversation queries the recent search endpoint for the tweets of a conversation, which the API
+// only returns for conversations with tweets from the last seven days
+func (tc *twitterClient) SearchConversation(conversationID string) ([]*Tweet, error) {
+	tweets := []*Tweet{}
+
+	nextToken := ""
+	for page := 0; page < maxSearchPages; page++ {
+		resp, err := tc.search(conversationID, nextToken)
+		if err != nil {
+			return nil, fmt.Errorf("conversation search error: %v", err)
+		}
+
+		includes, notes := resp.Includes.split()
+		for _, data := range resp.Data {
+			notes[data.ID] = data.NoteTweet
+		}
+		for _, data := range resp.Data {
+			tweet, pErr := ParseTweet(tw.CreateTweetDictionary(data.TweetObj, includes))
+			if pErr != nil {
+				return nil, pErr
+			}
+			tweet.setNote(notes[tweet.ID])
+			if tweet.QuotedTweet != nil {
+				tweet.QuotedTweet.setNote(notes[tweet.QuotedTweet.ID])
+			}
+			tweets = append(tweets, tweet)
+		}
+
+		if resp.Meta == nil || resp.Meta.NextToken == "" {
+			return tweets, nil
+		}
+		nextToken = resp.Meta.NextToken
+	}
+
+	// Page limit reached, so return the replies found so far
+	return tweets, nil
+}
+
+// search queries a page of the recent search endpoint for the tweets of a conversation
+func (tc *twitterClient) search(conversationID string, nextToken string) (*searchResponse, error) {
+	query := tweetLookupQuery()
+	query.Set("query", fmt.Sprintf("conversation_id:%s", conversationID))
+	query.Set("max_results", strconv.Itoa(maxSearchResults))
+	if nextToken != "" {
+		query.Set("next_token", nextToken)
+	}
+
+	searchResp := &searchResponse{}
+	err := tc.get(pathSearchRecent, pathSearchRecent, query, searchResp)
+	if err != nil {
+		return nil, err
+	}
+	return searchResp, nil
+}
+
+// searchResponse is a page of results of the recent search endpoint
+type searchResponse struct {
+	Data     []*lookupTweet  `json:"data"`
+	Includes *lookupIncludes `json:"includes"`
+	Errors   []*tw.ErrorObj  `json:"errors"`
+	Meta     *searchMeta     `json:"meta"`
+}
+
+// searchMeta describes a page of search results and how to request the next page
+type searchMeta struct {
+	ResultCount int    `json:"result_count"`
+	NextToken   string `json:"next_token,omitempty"`
+}
@@ -1,6 +1,10 @@
 package twitter
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	tw "github.com/g8rswimmer/go-twitter/v2"
 )
@@ -15,24 +20,58 @@ import (
 const (
 	// tweetReferencedTweetTypeRepliedTo is the field to use for following a thread's response chain
 	tweetReferencedTweetTypeRepliedTo = "replied_to"
+	// tweetReferencedTweetTypeQuoted is the field identifying a tweet quoted by another tweet
+	tweetReferencedTweetTypeQuoted = "quoted"
+
+	// MediaTypePhoto is the Attachment type of images
+	MediaTypePhoto = "photo"
+	// MediaTypeVideo is the Attachment type of videos
+	MediaTypeVideo = "video"
+	// MediaTypeGIF is the Attachment type of animated GIFs
+	MediaTypeGIF = "animated_gif"
 )
 
 // Tweet represents a Twitter tweet
 type Tweet struct {
-	ID             string       `json:"id"`
-	ConversationID string       `json:"conversation_id"`
-	URL            string       `json:"url"`
-	Text           string       `json:"text"`
-	CreatedAt      string       `json:"created_at"`
-	AuthorID       string       `json:"author_id"`
-	AuthorName     string       `json:"author_name"`
-	AuthorHandle   string       `json:"author_handle"`
-	RepliedToIDs   []string     `json:"replied_to_ids"`
-	Attachments    []Attachment `json:"attachments"`
+	ID             string        `json:"id"`
+	ConversationID string        `json:"conversation_id"`
+	URL            string        `json:"url"`
+	Text           string        `json:"text"`
+	CreatedAt      string        `json:"created_at"`
+	AuthorID       string        `json:"author_id"`
+	AuthorName     string        `json:"author_name"`
+	AuthorHandle   string        `json:"author_handle"`
+	RepliedToIDs   []string      `json:"replied_to_ids"`
+	Attachments    []Attachment  `json:"attachments"`
+	Poll           *Poll         `json:"poll,omitempty"`
+	PublicMetrics  *TweetMetrics `json:"public_metrics,omitempty"`
+	Entities       Entities      `json:"entities"`
+	NoteText       string        `json:"note_text,omitempty"`
+	NoteEntities   *Entities     `json:"note_entities,omitempty"`
+	QuotedTweet    *Tweet        `json:"quoted_tweet,omitempty"`
+	// Author is the profile of the tweet's author when looked up, which is saved once for a thread
+	// rather than with each tweet
+	Author *User `json:"-"`
 }
 
 // ParseTweet constructs a Tweet from the data returned by querying the Twitter API
 func ParseTweet(raw *tw.TweetDictionary) (*Tweet, error) {
+	tweet := parseTweet(raw)
+
+	for _, ref := range raw.ReferencedTweets {
+		if ref.Reference.Type != tweetReferencedTweetTypeQuoted || ref.TweetDictionary == nil {
+			continue
+		}
+		// Only a single level of quoted tweets is kept, as the API does not expand quotes of quotes
+		tweet.QuotedTweet = parseTweet(ref.TweetDictionary)
+		break
+	}
+
+	return tweet, nil
+}
+
+// parseTweet constructs a Tweet without its quoted tweet
+func parseTweet(raw *tw.TweetDictionary) *Tweet {
 	repliedToIDs := []string{}
 	for _, ref := range raw.Tweet.ReferencedTweets {
 		if ref.Type == tweetReferencedTweetTypeRepliedTo {
@@ -40,98 +79,290 @@ func ParseTweet(raw *tw.TweetDictionary) (*Tweet, error) {
 		}
 	}
 
-	attachments := []Attachment{}
-	for _, attachement := range raw.AttachmentMedia {
-		if attachement.URL != "" {
-			attachments = append(attachments, Attachment{
-				MediaKey: attachement.Key,
-				Type:     attachement.Type,
-				URL:      attachement.URL,
-			})
-			continue
-		}
-
-		// Add the attachment variant with the largest bit rate
-		type variantAttachment struct {
-			url     string
-			bitRate int
-		}
-		curVariant := variantAttachment{}
-		for _, variant := range attachement.Variants {
-			if variant.URL == "" {
-				continue
-			}
-			if variant.BitRate >= curVariant.bitRate {
-				curVariant.url = variant.URL
-				curVariant.bitRate = variant.BitRate
-			}
-		}
-		if curVariant.url != "" {
-			attachments = append(attachments, Attachment{
-				MediaKey: attachement.Key,
-				Type:     attachement.Type,
-				URL:      curVariant.url,
-			})
-		}
+	// The author of a referenced tweet is only included if it was requested as an expansion
+	authorName, authorHandle := "", ""
+	if raw.Author != nil {
+		authorName, authorHandle = raw.Author.Name, raw.Author.UserName
+	}
+	tweetURL := fmt.Sprintf("https://twitter.com/i/web/status/%s", raw.Tweet.ID)
+	if authorHandle != "" {
+		tweetURL = fmt.Sprintf("https://twitter.com/%s/status/%s", authorHandle, raw.Tweet.ID)
 	}
 
 	return &Tweet{
 		ID:             raw.Tweet.ID,
 		ConversationID: raw.Tweet.ConversationID,
-		URL:            fmt.Sprintf("https://twitter.com/%s/status/%s", raw.Author.UserName, raw.Tweet.ID),
+		URL:            tweetURL,
 		Text:           raw.Tweet.Text,
 		CreatedAt:      raw.Tweet.CreatedAt,
 		AuthorID:       raw.Tweet.AuthorID,
-		AuthorName:     raw.Author.Name,
-		AuthorHandle:   raw.Author.UserName,
+		AuthorName:     authorName,
+		AuthorHandle:   authorHandle,
 		RepliedToIDs:   repliedToIDs,
-		Attachments:    attachments,
-	}, nil
+		Attachments:    parseAttachments(raw.AttachmentMedia),
+		Poll:           parsePoll(raw.AttachmentPolls),
+		PublicMetrics:  parseTweetMetrics(raw.Tweet.PublicMetrics),
+		Entities:       parseEntities(raw.Tweet.Entities),
+		Author:         parseUser(raw.Author),
+	}
+}
+
+// LessID compares numeric tweet IDs, which increase over time
+func LessID(a string, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// CreatedTime parses the time at which a Tweet was posted
+func (t *Tweet) CreatedTime() (time.Time, error) {
+	return time.Parse(time.RFC3339, t.CreatedAt)
+}
+
+// setNote stores the full text of a tweet longer than 280 characters
+func (t *Tweet) setNote(note *noteTweet) {
+	if note == nil || note.Text == "" {
+		return
+	}
+	entities := parseEntities(note.Entities)
+	t.NoteText = note.Text
+	t.NoteEntities = &entities
+}
+
+// FullText returns a Tweet's complete text and its entities, which for tweets longer than 280
+// characters is the note text rather than the truncated text
+func (t *Tweet) FullText() (string, Entities) {
+	if t.NoteText != "" && t.NoteEntities != nil {
+		return t.NoteText, *t.NoteEntities
+	}
+	return t.Text, t.Entities
+}
+
+// ExpandedText returns a Tweet's complete text with t.co links replaced by the URLs they expand to
+func (t *Tweet) ExpandedText() string {
+	text, entities := t.FullText()
+	return entities.ExpandText(text)
+}
+
+// HTML returns a Tweet's complete text as HTML with links to expanded URLs, mentions, hashtags, and
+// cashtags
+func (t *Tweet) HTML() string {
+	text, entities := t.FullText()
+	return entities.HTML(text)
+}
+
+// parseAttachments constructs Attachments from media returned by the Twitter API
+func parseAttachments(media []*tw.MediaObj) []Attachment {
+	attachments := []Attachment{}
+	for _, attachement := range media {
+		attachment := Attachment{
+			MediaKey:        attachement.Key,
+			Type:            attachement.Type,
+			URL:             attachement.URL,
+			AltText:         attachement.AltText,
+			Width:           attachement.Width,
+			Height:          attachement.Height,
+			DurationMS:      attachement.DurationMS,
+			PreviewImageURL: attachement.PreviewImageURL,
+		}
+		if attachment.URL != "" {
+			attachments = append(attachments, attachment)
+			continue
+		}
+
+		// Keep all variants and default to the one with the largest bit rate, which is the only
+		// variant of a GIF
+		for _, variant := range attachement.Variants {
+			attachment.Variants = append(attachment.Variants, Variant{
+				URL:         variant.URL,
+				BitRate:     variant.BitRate,
+				ContentType: variant.ContentType,
+			})
+		}
+		attachment = attachment.WithVideoQuality(VideoQuality{})
+		if attachment.URL != "" {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments
 }
 
 // Attachment represents a media file attached to a Tweet
 type Attachment struct {
-	MediaKey string `json:"media_key"`
-	Type     string `json:"type"`
-	URL      string `json:"url"`
+	MediaKey        string    `json:"media_key"`
+	Type            string    `json:"type"`
+	URL             string    `json:"url"`
+	AltText         string    `json:"alt_text,omitempty"`
+	Width           int       `json:"width,omitempty"`
+	Height          int       `json:"height,omitempty"`
+	DurationMS      int       `json:"duration_ms,omitempty"`
+	PreviewImageURL string    `json:"preview_image_url,omitempty"`
+	Variants        []Variant `json:"variants,omitempty"`
+	ImageSize       ImageSize `json:"image_size,omitempty"`
+	Size            int64     `json:"size,omitempty"`
+	SHA256          string    `json:"sha256,omitempty"`
+	MIMEType        string    `json:"mime_type,omitempty"`
+}
+
+// IsGIF evaluates if an Attachment is an animated GIF, which Twitter serves as a silent video
+func (a Attachment) IsGIF() bool {
+	return a.Type == MediaTypeGIF
 }
 
-// Name constructs the file name to use for saving an Attachment
+// Name constructs the file name to use for saving an Attachment, using the extension of its MIME type
+// if known and the extension of its URL otherwise
 func (a Attachment) Name(tweetID string) string {
-	// Clean the file extension by removing any invalid params
-	ext := strings.SplitN(filepath.Ext(a.URL), "?", 2)[0]
+	ext := extensionByMIMEType(a.MIMEType)
+	if ext == "" {
+		// Clean the file extension by removing any invalid params
+		ext = strings.SplitN(filepath.Ext(a.URL), "?", 2)[0]
+	}
 	return fmt.Sprintf("tweet=%s-media_key=%s%s", tweetID, a.MediaKey, ext)
 }
 
-// Download saves an Attachment as a file
-func (a Attachment) Download(fileName string) error {
+// PosterName constructs the file name to use for saving the poster image of a video Attachment
+func (a Attachment) PosterName(tweetID string) string {
+	return fmt.Sprintf("tweet=%s-media_key=%s-poster.jpg", tweetID, a.MediaKey)
+}
+
+// ThumbnailName constructs the file name to use for saving a downscaled copy of an image Attachment
+func (a Attachment) ThumbnailName(tweetID string) string {
+	return fmt.Sprintf("tweet=%s-media_key=%s-thumb.jpg", tweetID, a.MediaKey)
+}
+
+// Download saves an Attachment as a file in dir using the provided HTTP client. Images are requested at
+// imageSize, falling back to the URL returned by the API if that size is unavailable, and the size
+// of the saved image is recorded in ImageSize. The MIME type of the file is detected from its contents
+// and used to name it, and its size and SHA-256 digest are recorded for later verification.
+func (a *Attachment) Download(client *http.Client, dir string, tweetID string, imageSize ImageSize) error {
 	if u, err := url.ParseRequestURI(a.URL); !(err == nil && u.Scheme != "" && u.Host != "") {
 		return fmt.Errorf("invalid attachment URL %s for media_key %s", a.URL, a.MediaKey)
 	}
-	resp, err := http.Get(a.URL)
+
+	savedSize := ImageSizeDefault
+	tmpFileName, file := "", downloadedFile{}
+	if a.Type == MediaTypePhoto && imageSize != ImageSizeDefault && imageSize != "" {
+		sized, err := sizedURL(a.URL, imageSize)
+		if err != nil {
+			return err
+		}
+		f, sizedFile, dErr := download(client, sized, dir)
+		// Fall back to the URL returned by the API only if the requested size is unavailable
+		if dErr != nil && !errors.As(dErr, &downloadStatusError{}) {
+			return dErr
+		}
+		if dErr == nil {
+			tmpFileName, file, savedSize = f, sizedFile, imageSize
+		}
+	}
+
+	if tmpFileName == "" {
+		f, defaultFile, err := download(client, a.URL, dir)
+		if err != nil {
+			return err
+		}
+		tmpFileName, file = f, defaultFile
+	}
+
+	if a.Type == MediaTypePhoto {
+		a.ImageSize = savedSize
+	}
+	a.MIMEType = file.mimeType
+	a.Size, a.SHA256 = file.size, file.sha256
+
+	// Name the file only once its MIME type is known
+	err := os.Rename(tmpFileName, filepath.Join(dir, a.Name(tweetID)))
 	if err != nil {
+		_ = os.Remove(tmpFileName)
 		return err
 	}
+	return nil
+}
+
+// DownloadPreviewImage saves the preview image of a video Attachment as a temporary file in dir using
+// the provided HTTP client, returning the file's name to be removed by the caller. An empty name is
+// returned if the Attachment has no preview image.
+func (a Attachment) DownloadPreviewImage(client *http.Client, dir string) (string, error) {
+	if a.PreviewImageURL == "" {
+		return "", nil
+	}
+	if u, err := url.ParseRequestURI(a.PreviewImageURL); !(err == nil && u.Scheme != "" && u.Host != "") {
+		return "", fmt.Errorf("invalid preview image URL %s for media_key %s", a.PreviewImageURL, a.MediaKey)
+	}
+
+	fileName, _, err := download(client, a.PreviewImageURL, dir)
+	return fileName, err
+}
+
+// maxDownloadSize is the largest attachment file that is downloaded. Downloads are streamed to disk
+// rather than held in memory, so the limit only stops a runaway response from filling the disk and is
+// well above the size of the longest videos Twitter serves.
+const maxDownloadSize = 1024 * 1024 * 1024
+
+// downloadStatusError is returned when a download fails with an unsuccessful status code
+type downloadStatusError struct {
+	url        string
+	statusCode int
+}
+
+func (e downloadStatusError) Error() string {
+	return fmt.Sprintf("download of %s failed with status code: %d", e.url, e.statusCode)
+}
+
+// downloadedFile describes a file saved by download
+type downloadedFile struct {
+	size     int64
+	sha256   string
+	mimeType string
+}
+
+// download saves the contents of a URL as a temporary file in dir, returning the file's name and
+// description, so that an existing file is only replaced by a complete download
+func download(client *http.Client, fileURL string, dir string) (string, downloadedFile, error) {
+	file := downloadedFile{}
+
+	resp, err := client.Get(fileURL)
+	if err != nil {
+		return "", file, err
+	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download of %s failed with status code: %d", a.URL, resp.StatusCode)
+		return "", file, downloadStatusError{url: fileURL, statusCode: resp.StatusCode}
+	}
+
+	// Read the beginning of the file to detect its MIME type
+	head := make([]byte, sniffLen)
+	n, rErr := io.ReadFull(resp.Body, head)
+	if rErr != nil && !errors.Is(rErr, io.EOF) && !errors.Is(rErr, io.ErrUnexpectedEOF) {
+		return "", file, rErr
 	}
+	head = head[:n]
+	file.mimeType = detectMIMEType(head, resp.Header.Get("Content-Type"))
 
-	f, fErr := os.Create(filepath.Clean(fileName))
+	f, fErr := os.CreateTemp(dir, ".download-*")
 	if fErr != nil {
-		return fErr
+		return "", file, fErr
 	}
-	defer func() {
-		_ = f.Close()
-	}()
 
-	_, cErr := io.Copy(f, io.LimitReader(resp.Body, 1024*1024))
+	h := sha256.New()
+	body := io.MultiReader(bytes.NewReader(head), resp.Body)
+	size, cErr := io.Copy(io.MultiWriter(f, h), io.LimitReader(body, maxDownloadSize+1))
+	if cErr == nil && size > maxDownloadSize {
+		cErr = fmt.Errorf("download of %s exceeds the maximum size of %d bytes", fileURL, maxDownloadSize)
+	}
+	if clErr := f.Close(); cErr == nil {
+		cErr = clErr
+	}
 	if cErr != nil {
-		return cErr
+		_ = os.Remove(f.Name())
+		return "", file, cErr
 	}
 
-	return nil
+	file.size = size
+	file.sha256 = hex.EncodeToString(h.Sum(nil))
+	return f.Name(), file, nil
 }
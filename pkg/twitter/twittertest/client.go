@@ -0,0 +1,21 @@
+package twittertest
+
+import (
+	"testing"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter"
+)
+
+// NewClient starts a server with NewServer that is closed when the test completes and returns a
+// twitter.Client using it as its Host
+func NewClient(t testing.TB, dir string) twitter.Client {
+	t.Helper()
+	server := NewServer(dir)
+	t.Cleanup(server.Close)
+
+	client, err := twitter.NewClient("token", twitter.Options{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to construct client: %v", err)
+	}
+	return client
+}
@@ -0,0 +1,41 @@
+// Command fakeapi runs a twittertest.Server on a fixed address for demos and manual testing
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dkaslovsky/thread-safe/pkg/twitter/twittertest"
+)
+
+func main() {
+	var addr, dir string
+	var rateLimit int
+	var rateWindow time.Duration
+	flag.StringVar(&addr, "addr", "127.0.0.1:8080", "address on which to listen")
+	flag.StringVar(&dir, "fixtures", "pkg/twitter/twittertest/testdata/Nathan_MacKinnon_2018", "path to fixture directory")
+	flag.IntVar(&rateLimit, "rate-limit", 0, "number of API requests allowed per rate limit window (no limit if zero)")
+	flag.DurationVar(&rateWindow, "rate-window", 15*time.Minute, "duration of each rate limit window")
+	flag.Parse()
+
+	handler := twittertest.NewHandler(dir)
+	if rateLimit > 0 {
+		handler = twittertest.NewRateLimitedHandler(handler, rateLimit, rateWindow)
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("serving fixtures from %s at http://%s\n", dir, addr)
+	err := server.ListenAndServe()
+	if err != nil {
+		fmt.Printf("fakeapi: %v\n", err)
+		os.Exit(1)
+	}
+}
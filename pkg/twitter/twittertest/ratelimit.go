@@ -0,0 +1,67 @@
+package twittertest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pathAPI is the URL path prefix of the API endpoints, which are subject to rate limits unlike media
+const pathAPI = "/2/"
+
+// NewRateLimitedHandler wraps an http.Handler to enforce a rate limit of limit API requests per window,
+// reporting the limit in the response headers used by the Twitter API and rejecting requests exceeding it
+// with status 429. All endpoints share a single limit.
+func NewRateLimitedHandler(next http.Handler, limit int, window time.Duration) http.Handler {
+	return &rateLimitedHandler{
+		next:   next,
+		limit:  limit,
+		window: window,
+	}
+}
+
+type rateLimitedHandler struct {
+	next   http.Handler
+	limit  int
+	window time.Duration
+
+	mu    sync.Mutex
+	count int
+	reset time.Time
+}
+
+func (h *rateLimitedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, pathAPI) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	if !now.Before(h.reset) {
+		h.count = 0
+		h.reset = now.Add(h.window)
+	}
+	h.count++
+	remaining := h.limit - h.count
+	limited := h.count > h.limit
+	reset := h.reset
+	h.mu.Unlock()
+
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("x-rate-limit-limit", strconv.Itoa(h.limit))
+	w.Header().Set("x-rate-limit-remaining", strconv.Itoa(remaining))
+	w.Header().Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+
+	if limited {
+		writeJSON(w, http.StatusTooManyRequests, []byte(errTooManyRequests))
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+const errTooManyRequests = `{"title":"Too Many Requests","type":"about:blank","status":429,"detail":"Too Many Requests"}`
@@ -0,0 +1,149 @@
+// Package twittertest provides a fake Twitter API server for running thread-safe without network access
+package twittertest
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// HostPlaceholder is replaced by the server's URL in served fixture files, allowing fixtures to
+	// reference media hosted by the server
+	HostPlaceholder = "{{HOST}}"
+
+	// dirNameTweets is the fixture directory containing tweet lookup responses named <id>.json
+	dirNameTweets = "tweets"
+	// dirNameMedia is the fixture directory containing media files
+	dirNameMedia = "media"
+	// dirNameSearch is the fixture directory containing conversation search responses named
+	// <conversation_id>.json, with later pages named <conversation_id>-<next_token>.json
+	dirNameSearch = "search"
+
+	// pathTweets is the URL path prefix of the tweet lookup endpoint
+	pathTweets = "/2/tweets/"
+	// pathMedia is the URL path prefix for media files
+	pathMedia = "/media/"
+	// pathSearch is the URL path of the recent search endpoint
+	pathSearch = "/2/tweets/search/recent"
+
+	// queryPrefixConversation is the prefix of a search query for the tweets of a conversation, the only
+	// search query supported by the server
+	queryPrefixConversation = "conversation_id:"
+)
+
+// NewServer starts an httptest.Server serving the handler returned by NewHandler, to be used as the
+// Host of a twitter.Client
+func NewServer(dir string) *httptest.Server {
+	return httptest.NewServer(NewHandler(dir))
+}
+
+// NewHandler returns an http.Handler faking the Twitter API v2 tweet lookup and recent search endpoints
+// and media hosting using fixtures from dir, which contains a "tweets" directory of raw tweet lookup
+// responses named <id>.json, a "search" directory of raw conversation search responses, and a "media"
+// directory of files served under the /media/ path, with images requested at a size by the "name" query
+// parameter served from files named <name>-<size><ext>
+func NewHandler(dir string) http.Handler {
+	h := &handler{dir: dir}
+	mux := http.NewServeMux()
+	mux.HandleFunc(pathTweets, h.handleTweet)
+	mux.HandleFunc(pathSearch, h.handleSearch)
+	mux.HandleFunc(pathMedia, h.handleMedia)
+	return mux
+}
+
+type handler struct {
+	dir string
+}
+
+func (h *handler) handleTweet(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeJSON(w, http.StatusUnauthorized, []byte(errUnauthorized))
+		return
+	}
+
+	id := path.Base(r.URL.Path)
+	b, err := os.ReadFile(filepath.Join(h.dir, dirNameTweets, id+".json"))
+	if err != nil {
+		// The API reports unknown tweets as errors in a successful response
+		writeJSON(w, http.StatusOK, []byte(fmt.Sprintf(errNotFound, id, id)))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bytes.ReplaceAll(b, []byte(HostPlaceholder), []byte(hostURL(r))))
+}
+
+func (h *handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeJSON(w, http.StatusUnauthorized, []byte(errUnauthorized))
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+	if !strings.HasPrefix(query, queryPrefixConversation) {
+		writeJSON(w, http.StatusBadRequest, []byte(fmt.Sprintf(errInvalidQuery, query)))
+		return
+	}
+	name := path.Base(strings.TrimPrefix(query, queryPrefixConversation))
+	if nextToken := r.URL.Query().Get("next_token"); nextToken != "" {
+		name = fmt.Sprintf("%s-%s", name, path.Base(nextToken))
+	}
+
+	b, err := os.ReadFile(filepath.Join(h.dir, dirNameSearch, name+".json"))
+	if err != nil {
+		// The API reports searches without results as a successful response with no data
+		writeJSON(w, http.StatusOK, []byte(noResults))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bytes.ReplaceAll(b, []byte(HostPlaceholder), []byte(hostURL(r))))
+}
+
+func (h *handler) handleMedia(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	// Images requested at a named size are served from <name>-<size><ext>, so sizes without a
+	// fixture file are unavailable
+	if size := r.URL.Query().Get("name"); size != "" {
+		ext := path.Ext(name)
+		name = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), path.Base(size), ext)
+	}
+	b, err := os.ReadFile(filepath.Join(h.dir, dirNameMedia, name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	_, _ = w.Write(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, b []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+// hostURL reconstructs the base URL used to reach the server
+func hostURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+const errUnauthorized = `{"title":"Unauthorized","type":"about:blank","status":401,"detail":"Unauthorized"}`
+
+const errInvalidQuery = `{"title":"Invalid Request","type":"https://api.twitter.com/2/problems/invalid-request","detail":"One or more parameters to your request was invalid.","errors":[{"message":"unsupported query: %s"}]}`
+
+const noResults = `{"meta":{"result_count":0}}`
+
+const errNotFound = `{"errors":[{"value":"%s","detail":"Could not find tweet with id: [%s].","title":"Not Found Error","resource_type":"tweet","parameter":"id","type":"https://api.twitter.com/2/problems/resource-not-found"}]}`
@@ -0,0 +1,122 @@
+package twitter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tw "github.com/g8rswimmer/go-twitter/v2"
+)
+
+const (
+	// profileImageSizeNormal is the suffix of the small profile image URL returned by the API
+	profileImageSizeNormal = "_normal"
+	// profileImageSizeLarge is the suffix of the largest square profile image
+	profileImageSizeLarge = "_400x400"
+)
+
+// User represents the profile of a tweet's author
+type User struct {
+	ID              string       `json:"id"`
+	Name            string       `json:"name"`
+	Handle          string       `json:"handle"`
+	Description     string       `json:"description,omitempty"`
+	Location        string       `json:"location,omitempty"`
+	CreatedAt       string       `json:"created_at,omitempty"`
+	Verified        bool         `json:"verified,omitempty"`
+	Protected       bool         `json:"protected,omitempty"`
+	PublicMetrics   *UserMetrics `json:"public_metrics,omitempty"`
+	ProfileImageURL string       `json:"profile_image_url,omitempty"`
+	Avatar          string       `json:"avatar,omitempty"`
+}
+
+// UserMetrics are the public counts of a User's activity at the time it was looked up
+type UserMetrics struct {
+	Followers   int `json:"followers"`
+	Following   int `json:"following"`
+	TweetCount  int `json:"tweet_count"`
+	ListedCount int `json:"listed_count"`
+}
+
+// parseUser constructs a User from a user object returned by the Twitter API, returning nil for a nil
+// user object
+func parseUser(raw *tw.UserObj) *User {
+	if raw == nil {
+		return nil
+	}
+	user := &User{
+		ID:              raw.ID,
+		Name:            raw.Name,
+		Handle:          raw.UserName,
+		Description:     raw.Description,
+		Location:        raw.Location,
+		CreatedAt:       raw.CreatedAt,
+		Verified:        raw.Verified,
+		Protected:       raw.Protected,
+		ProfileImageURL: raw.ProfileImageURL,
+	}
+	if metrics := raw.PublicMetrics; metrics != nil {
+		user.PublicMetrics = &UserMetrics{
+			Followers:   metrics.Followers,
+			Following:   metrics.Following,
+			TweetCount:  metrics.Tweets,
+			ListedCount: metrics.Listed,
+		}
+	}
+	return user
+}
+
+// URL returns the address of a User's profile
+func (u User) URL() string {
+	return fmt.Sprintf("https://twitter.com/%s", u.Handle)
+}
+
+// AvatarName constructs the file name to use for saving a User's profile image with the extension ext
+func (u User) AvatarName(ext string) string {
+	return fmt.Sprintf("avatar=%s%s", u.ID, ext)
+}
+
+// DownloadAvatar saves a User's profile image as a file in dir using the provided HTTP client, recording
+// the name of the file in Avatar. The largest size of the image is requested, falling back to the URL
+// returned by the API if that size is unavailable.
+func (u *User) DownloadAvatar(client *http.Client, dir string) error {
+	if u.ProfileImageURL == "" {
+		return nil
+	}
+	if parsed, err := url.ParseRequestURI(u.ProfileImageURL); !(err == nil && parsed.Scheme != "" && parsed.Host != "") {
+		return fmt.Errorf("invalid profile image URL %s for user %s", u.ProfileImageURL, u.Handle)
+	}
+
+	tmpFileName, file := "", downloadedFile{}
+	if large := strings.Replace(u.ProfileImageURL, profileImageSizeNormal, profileImageSizeLarge, 1); large != u.ProfileImageURL {
+		f, largeFile, err := download(client, large, dir)
+		if err != nil && !errors.As(err, &downloadStatusError{}) {
+			return err
+		}
+		tmpFileName, file = f, largeFile
+	}
+	if tmpFileName == "" {
+		f, normalFile, err := download(client, u.ProfileImageURL, dir)
+		if err != nil {
+			return err
+		}
+		tmpFileName, file = f, normalFile
+	}
+
+	ext := extensionByMIMEType(file.mimeType)
+	if ext == "" {
+		ext = strings.SplitN(filepath.Ext(u.ProfileImageURL), "?", 2)[0]
+	}
+	name := u.AvatarName(ext)
+	err := os.Rename(tmpFileName, filepath.Join(dir, name))
+	if err != nil {
+		_ = os.Remove(tmpFileName)
+		return err
+	}
+	u.Avatar = name
+	return nil
+}